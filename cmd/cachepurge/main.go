@@ -0,0 +1,71 @@
+// Command cachepurge deletes the cache keys matching a pattern, e.g. after the JSON shape of a model changed.
+//
+//	go run ./cmd/cachepurge -f cmd/cachepurge/etc/cachepurge.yaml
+//
+// DryRun defaults to true, set it to false in the config file to actually delete the keys.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/huof6829/gorm-zero/gormc"
+	"github.com/zeromicro/go-zero/core/conf"
+)
+
+var configFile = flag.String("f", "etc/cachepurge.yaml", "the config file")
+
+type Config struct {
+	Redis     gormc.RedisConfig
+	Namespace string        `json:",optional"` // namespace of the conns, see gormc.WithNamespace, prefixed to Pattern
+	Pattern   string        // key pattern, e.g. cache:order:*
+	BatchSize int64         `json:",default=500"`  // SCAN COUNT hint and UNLINK batch size
+	RateLimit int           `json:",default=1000"` // maximum keys deleted per second, 0 means unlimited
+	DryRun    bool          `json:",default=true"` // only count the matched keys
+	Timeout   time.Duration `json:",default=10m"`
+}
+
+func main() {
+	flag.Parse()
+
+	var c Config
+	conf.MustLoad(*configFile, &c)
+
+	os.Exit(run(c))
+}
+
+// run purges the keys and returns the exit code, so that the deferred calls run before exiting.
+func run(c Config) int {
+	cache, err := gormc.NewRedisCache(c.Redis, 0)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer cache.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.Timeout)
+	defer cancel()
+
+	pattern := c.Pattern
+	if len(c.Namespace) > 0 {
+		pattern = c.Namespace + ":" + pattern
+	}
+	result, err := cache.PurgeKeysCtx(ctx, gormc.ScanOptions{
+		Pattern:   pattern,
+		BatchSize: c.BatchSize,
+		RateLimit: c.RateLimit,
+		DryRun:    c.DryRun,
+	})
+	fmt.Printf("pattern: %s, dry-run: %v, matched: %d, deleted: %d\n", pattern, result.DryRun, result.Matched, result.Deleted)
+	for _, key := range result.Samples {
+		fmt.Printf("  %s\n", key)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
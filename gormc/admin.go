@@ -0,0 +1,251 @@
+package gormc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+type (
+	// AdminKeyer returns the cache keys of the row of a model with the given primary key,
+	// e.g. FindOne followed by GetCacheKeys for generated models.
+	AdminKeyer func(ctx context.Context, primary string) ([]string, error)
+
+	// AdminAuditEvent is an operation done through the admin handler.
+	AdminAuditEvent struct {
+		Action     string   // get, delete, delete-model, stats or unauthorized
+		User       string   // the user returned by the auth hook
+		RemoteAddr string   // address of the caller
+		Keys       []string // the keys read or deleted
+		Err        error    // the error of the operation, if any
+	}
+
+	// AdminOption customizes the admin handler.
+	AdminOption func(h *adminHandler)
+
+	adminHandler struct {
+		cache  *RedisCache
+		auth   func(r *http.Request) (user string, ok bool)
+		audit  func(r *http.Request, event AdminAuditEvent)
+		keyers map[string]AdminKeyer
+	}
+
+	adminKeyResponse struct {
+		Key      string          `json:"key"`
+		Exists   bool            `json:"exists"`
+		NotFound bool            `json:"notFound,omitempty"` // a not found placeholder is cached
+		TTL      int64           `json:"ttl"`                // seconds, -1 means no expiry
+		Value    json.RawMessage `json:"value,omitempty"`
+		Raw      string          `json:"raw,omitempty"` // values that are not json
+	}
+
+	adminDeleteResponse struct {
+		Keys []string `json:"keys"`
+	}
+
+	adminStatsResponse struct {
+		Mode      string   `json:"mode"`
+		Migrating bool     `json:"migrating"`
+		HotKeys   []HotKey `json:"hotKeys"`
+		Models    []string `json:"models"`
+	}
+)
+
+// WithAdminAuth sets the auth hook of the admin handler, it returns the user calling and whether it is allowed.
+func WithAdminAuth(auth func(r *http.Request) (user string, ok bool)) AdminOption {
+	return func(h *adminHandler) {
+		h.auth = auth
+	}
+}
+
+// WithAdminAudit sets the audit hook of the admin handler, the operations are logged by default.
+func WithAdminAudit(audit func(r *http.Request, event AdminAuditEvent)) AdminOption {
+	return func(h *adminHandler) {
+		h.audit = audit
+	}
+}
+
+// WithAdminKeyer registers the keyer of model, so that its rows can be deleted by primary key.
+func WithAdminKeyer(model string, keyer AdminKeyer) AdminOption {
+	return func(h *adminHandler) {
+		h.keyers[model] = keyer
+	}
+}
+
+// NewAdminHandler returns an http.Handler to inspect and delete the entries of c, it serves:
+//
+//	GET    .../key?key=cache:order:id:123              value and TTL of a key
+//	DELETE .../key?key=cache:order:id:123&key=...      delete keys
+//	DELETE .../model?model=order&primary=123           delete the keys of a row with a registered keyer
+//	GET    .../stats                                   mode, hot keys and registered models
+//
+// Requests are rejected unless WithAdminAuth allows them. With go-zero rest, add a route of each path
+// pointing to the same handler. Deletes bypass the cache mode, they always reach redis.
+func NewAdminHandler(c *RedisCache, opts ...AdminOption) http.Handler {
+	h := &adminHandler{
+		cache:  c,
+		audit:  logAdminAudit,
+		keyers: make(map[string]AdminKeyer),
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
+
+func (h *adminHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var user string
+	ok := false
+	if h.auth != nil {
+		user, ok = h.auth(r)
+	}
+	if !ok {
+		h.audit(r, AdminAuditEvent{Action: "unauthorized", User: user, RemoteAddr: r.RemoteAddr})
+		http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+		return
+	}
+
+	path := strings.TrimSuffix(r.URL.Path, "/")
+	switch {
+	case strings.HasSuffix(path, "/key") && r.Method == http.MethodGet:
+		h.getKey(w, r, user)
+	case strings.HasSuffix(path, "/key") && r.Method == http.MethodDelete:
+		h.deleteKeys(w, r, user, "delete", r.URL.Query()["key"])
+	case strings.HasSuffix(path, "/model") && r.Method == http.MethodDelete:
+		h.deleteModel(w, r, user)
+	case strings.HasSuffix(path, "/stats") && r.Method == http.MethodGet:
+		h.stats(w, r, user)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (h *adminHandler) getKey(w http.ResponseWriter, r *http.Request, user string) {
+	key := r.URL.Query().Get("key")
+	if len(key) == 0 {
+		http.Error(w, "key is required", http.StatusBadRequest)
+		return
+	}
+
+	resp, err := h.lookup(r.Context(), key)
+	h.audit(r, AdminAuditEvent{Action: "get", User: user, RemoteAddr: r.RemoteAddr, Keys: []string{key}, Err: err})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeAdminJson(w, resp)
+}
+
+func (h *adminHandler) lookup(ctx context.Context, key string) (adminKeyResponse, error) {
+	resp := adminKeyResponse{Key: key}
+	client := h.cache.primary()
+	data, err := client.Get(ctx, key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return resp, nil
+	}
+	if err != nil {
+		return resp, err
+	}
+
+	ttl, err := client.TTL(ctx, key).Result()
+	if err != nil {
+		return resp, err
+	}
+	resp.Exists = true
+	resp.TTL = int64(ttl / time.Second)
+	if ttl < 0 {
+		resp.TTL = -1
+	}
+
+	if data, err = decompress(data); err != nil {
+		return resp, err
+	}
+	switch {
+	case string(data) == notFoundPlaceholder:
+		resp.NotFound = true
+	case json.Valid(data):
+		resp.Value = data
+	default:
+		resp.Raw = string(data)
+	}
+	return resp, nil
+}
+
+func (h *adminHandler) deleteModel(w http.ResponseWriter, r *http.Request, user string) {
+	model := r.URL.Query().Get("model")
+	primary := r.URL.Query().Get("primary")
+	keyer, ok := h.keyers[model]
+	if !ok || len(primary) == 0 {
+		http.Error(w, "a registered model and primary are required", http.StatusBadRequest)
+		return
+	}
+
+	keys, err := keyer(r.Context(), primary)
+	if err != nil {
+		h.audit(r, AdminAuditEvent{Action: "delete-model", User: user, RemoteAddr: r.RemoteAddr, Err: err})
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	h.deleteKeys(w, r, user, "delete-model", keys)
+}
+
+func (h *adminHandler) deleteKeys(w http.ResponseWriter, r *http.Request, user, action string, keys []string) {
+	if len(keys) == 0 {
+		http.Error(w, "key is required", http.StatusBadRequest)
+		return
+	}
+
+	h.cache.evictLocal(keys...)
+	// one by one, the keys may live in different slots in cluster mode
+	var err error
+	for _, key := range keys {
+		if err = h.cache.del(r.Context(), key); err != nil {
+			break
+		}
+	}
+	h.audit(r, AdminAuditEvent{Action: action, User: user, RemoteAddr: r.RemoteAddr, Keys: keys, Err: err})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeAdminJson(w, adminDeleteResponse{Keys: keys})
+}
+
+func (h *adminHandler) stats(w http.ResponseWriter, r *http.Request, user string) {
+	models := make([]string, 0, len(h.keyers))
+	for model := range h.keyers {
+		models = append(models, model)
+	}
+	sort.Strings(models)
+
+	h.audit(r, AdminAuditEvent{Action: "stats", User: user, RemoteAddr: r.RemoteAddr})
+	writeAdminJson(w, adminStatsResponse{
+		Mode:      h.cache.Mode().String(),
+		Migrating: h.cache.secondary() != nil,
+		HotKeys:   h.cache.HotKeys(),
+		Models:    models,
+	})
+}
+
+func logAdminAudit(r *http.Request, event AdminAuditEvent) {
+	logger := logx.WithContext(r.Context())
+	if event.Err != nil {
+		logger.Errorf("gormc admin: action=%s user=%q remote=%s keys=%v err=%v",
+			event.Action, event.User, event.RemoteAddr, event.Keys, event.Err)
+		return
+	}
+	logger.Infof("gormc admin: action=%s user=%q remote=%s keys=%v",
+		event.Action, event.User, event.RemoteAddr, event.Keys)
+}
+
+func writeAdminJson(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,84 @@
+package gormc_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/huof6829/gorm-zero/gormc"
+)
+
+func TestAdminHandler(t *testing.T) {
+	mr, cache := setupTestCache(t)
+	var events []gormc.AdminAuditEvent
+	handler := gormc.NewAdminHandler(cache,
+		gormc.WithAdminAuth(func(r *http.Request) (string, bool) {
+			return "alice", r.Header.Get("X-Token") == "secret"
+		}),
+		gormc.WithAdminAudit(func(r *http.Request, event gormc.AdminAuditEvent) {
+			events = append(events, event)
+		}),
+		gormc.WithAdminKeyer("order", func(ctx context.Context, primary string) ([]string, error) {
+			return []string{"order:id:" + primary, "order:sn:SN" + primary}, nil
+		}),
+	)
+	do := func(method, target string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, nil)
+		req.Header.Set("X-Token", "secret")
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	// 未授权
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/cache/stats", nil))
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("Expected 403 without auth, got %d", w.Code)
+	}
+
+	mr.Set("order:id:123", `{"id":123}`)
+	mr.SetTTL("order:id:123", 60e9)
+	mr.Set("order:sn:SN123", "123")
+
+	w = do(http.MethodGet, "/admin/cache/key?key=order:id:123")
+	var got struct {
+		Exists bool
+		TTL    int64
+		Value  json.RawMessage
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil || !got.Exists || got.TTL != 60 ||
+		string(got.Value) != `{"id":123}` {
+		t.Errorf("unexpected key response: %s", w.Body.String())
+	}
+
+	// 按模型和主键删除
+	w = do(http.MethodDelete, "/admin/cache/model?model=order&primary=123")
+	if w.Code != http.StatusOK || mr.Exists("order:id:123") || mr.Exists("order:sn:SN123") {
+		t.Errorf("Expected the keys of the row to be deleted, got %d %s", w.Code, w.Body.String())
+	}
+	if w = do(http.MethodDelete, "/admin/cache/model?model=user&primary=1"); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for unregistered models, got %d", w.Code)
+	}
+
+	mr.Set("k1", "1")
+	if w = do(http.MethodDelete, "/admin/cache/key?key=k1"); w.Code != http.StatusOK || mr.Exists("k1") {
+		t.Errorf("Expected k1 to be deleted, got %d", w.Code)
+	}
+
+	w = do(http.MethodGet, "/admin/cache/stats")
+	var stats struct {
+		Mode   string
+		Models []string
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &stats); err != nil || stats.Mode != "normal" || len(stats.Models) != 1 {
+		t.Errorf("unexpected stats: %s", w.Body.String())
+	}
+
+	if len(events) != 5 || events[0].Action != "unauthorized" || events[2].Action != "delete-model" ||
+		events[2].User != "alice" || len(events[2].Keys) != 2 {
+		t.Errorf("unexpected audit events: %+v", events)
+	}
+}
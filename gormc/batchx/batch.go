@@ -33,36 +33,71 @@ func BatchExecCtxV2[DBModel any, Model BatchExecModel[DBModel]](
 ) error {
 	cacheKeys := getCacheKeysByMultiData(model, olds)
 	err := model.ExecCtx(ctx, func(conn *gorm.DB) error {
-		db := conn
-		commitTx := false
-		if tx != nil {
-			db = tx
-		} else {
-			db = db.Begin()
-			commitTx = true
-		}
-		defer func() {
-			if commitTx {
-				if r := recover(); r != nil {
-					db.Rollback()
-					panic(r)
-				}
-			}
-		}()
-		err := exec(db)
+		return execInTx(conn, tx, exec)
+	}, cacheKeys...)
+	return err
+}
+
+// BatchInsertModel is the model of BatchInsertCtx.
+type BatchInsertModel[DBModel any] interface {
+	GetCacheKeys(data *DBModel) []string
+	ExecWithKeysCtx(ctx context.Context, execCtx gormc.ExecCtxFn, keysFn func() []string) error
+}
+
+// BatchInsertCtx runs exec inserting news in tx, or in a new transaction if tx is nil.
+// The cache keys are computed after the insert, so that the auto-increment ids set by exec on
+// the elements of news, e.g. by creating &news[i], are invalidated and added to the Bloom filter.
+func BatchInsertCtx[DBModel any, Model BatchInsertModel[DBModel]](
+	ctx context.Context,
+	model Model,
+	news []DBModel,
+	exec func(db *gorm.DB) error,
+	tx *gorm.DB, // pass tx here, can be nil
+) error {
+	if len(news) == 0 {
+		return nil
+	}
+	return model.ExecWithKeysCtx(ctx, func(conn *gorm.DB) error {
+		return execInTx(conn, tx, exec)
+	}, func() []string {
+		return getCacheKeysByMultiData(model, news)
+	})
+}
+
+// execInTx runs exec in tx, or in a new transaction of conn if tx is nil.
+func execInTx(conn, tx *gorm.DB, exec func(db *gorm.DB) error) error {
+	db := conn
+	commitTx := false
+	if tx != nil {
+		db = tx
+	} else {
+		db = db.Begin()
+		commitTx = true
+	}
+	defer func() {
 		if commitTx {
-			if err != nil {
+			if r := recover(); r != nil {
 				db.Rollback()
-				return err
+				panic(r)
 			}
-			return db.Commit().Error
 		}
-		return err
-	}, cacheKeys...)
+	}()
+	err := exec(db)
+	if commitTx {
+		if err != nil {
+			db.Rollback()
+			return err
+		}
+		return db.Commit().Error
+	}
 	return err
 }
 
-func getCacheKeysByMultiData[DBModel any, Model BatchExecModel[DBModel]](m Model, data []DBModel) []string {
+type cacheKeysModel[DBModel any] interface {
+	GetCacheKeys(data *DBModel) []string
+}
+
+func getCacheKeysByMultiData[DBModel any, Model cacheKeysModel[DBModel]](m Model, data []DBModel) []string {
 	if len(data) == 0 {
 		return []string{}
 	}
@@ -0,0 +1,363 @@
+package gormc
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/zeromicro/go-zero/core/hash"
+	"github.com/zeromicro/go-zero/core/logx"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// bloomRebuildExpiry is the TTL of an unfinished redis bloom filter rebuild.
+const bloomRebuildExpiry = time.Hour
+
+var (
+	// language=lua
+	bloomAddScript = redis.NewScript(`
+local rebuilding = redis.call("EXISTS", KEYS[2]) == 1
+local ready = redis.call("EXISTS", KEYS[1]) == 1
+for _, offset in ipairs(ARGV) do
+	if ready then
+		redis.call("SETBIT", KEYS[1], offset, 1)
+	end
+	if rebuilding then
+		redis.call("SETBIT", KEYS[2], offset, 1)
+	end
+end
+return 1`)
+
+	// language=lua
+	bloomExistsScript = redis.NewScript(`
+if redis.call("EXISTS", KEYS[1]) == 0 then
+	return 1
+end
+for _, offset in ipairs(ARGV) do
+	if redis.call("GETBIT", KEYS[1], offset) == 0 then
+		return 0
+	end
+end
+return 1`)
+)
+
+type (
+	// BloomFilter tests whether a cache key may exist.
+	// A filter that has never been rebuilt reports that every key may exist.
+	BloomFilter interface {
+		// AddCtx adds keys into the filter.
+		AddCtx(ctx context.Context, keys ...string) error
+		// ExistsCtx reports whether key may exist, false means it definitely doesn't.
+		ExistsCtx(ctx context.Context, key string) (bool, error)
+		// RebuildCtx rebuilds the filter with the keys produced by load.
+		RebuildCtx(ctx context.Context, load BloomLoader) error
+	}
+
+	// BloomLoader produces all the existing keys by calling add, usually by scanning a table.
+	BloomLoader func(ctx context.Context, add func(keys ...string) error) error
+
+	// LocalBloomFilter is an in-process BloomFilter.
+	// It only sees the keys added by its own process, so it is only correct with a single writer process:
+	// with several instances, the keys inserted by another one are rejected until the next rebuild.
+	LocalBloomFilter struct {
+		mu         sync.RWMutex
+		bits       []uint64
+		rebuilding []uint64
+		ready      bool
+		m          uint64
+		k          uint64
+	}
+
+	// RedisBloomFilter is a BloomFilter stored in redis, shared by all the instances.
+	RedisBloomFilter struct {
+		cache      *RedisCache
+		key        string
+		rebuildKey string
+		m          uint64
+		k          uint64
+	}
+
+	bloomChecker struct {
+		filter   BloomFilter
+		prefixes []string
+	}
+)
+
+// NewLocalBloomFilter returns a LocalBloomFilter sized for expected keys with the false positive rate fpRate.
+// Use NewRedisBloomFilter unless this process is the only writer of the table.
+func NewLocalBloomFilter(expected uint64, fpRate float64) *LocalBloomFilter {
+	m, k := bloomSize(expected, fpRate)
+	return &LocalBloomFilter{
+		bits: make([]uint64, (m+63)/64),
+		m:    m,
+		k:    k,
+	}
+}
+
+// AddCtx adds keys into the filter.
+func (f *LocalBloomFilter) AddCtx(_ context.Context, keys ...string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, key := range keys {
+		for _, offset := range bloomOffsets(key, f.m, f.k) {
+			f.bits[offset/64] |= 1 << (offset % 64)
+			if f.rebuilding != nil {
+				f.rebuilding[offset/64] |= 1 << (offset % 64)
+			}
+		}
+	}
+	return nil
+}
+
+// ExistsCtx reports whether key may exist.
+func (f *LocalBloomFilter) ExistsCtx(_ context.Context, key string) (bool, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	if !f.ready {
+		return true, nil
+	}
+	for _, offset := range bloomOffsets(key, f.m, f.k) {
+		if f.bits[offset/64]&(1<<(offset%64)) == 0 {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// RebuildCtx rebuilds the filter with the keys produced by load,
+// keys added during the rebuild are kept.
+func (f *LocalBloomFilter) RebuildCtx(ctx context.Context, load BloomLoader) error {
+	f.mu.Lock()
+	if f.rebuilding != nil {
+		f.mu.Unlock()
+		return fmt.Errorf("bloom filter is being rebuilt")
+	}
+	f.rebuilding = make([]uint64, len(f.bits))
+	f.mu.Unlock()
+
+	err := load(ctx, func(keys ...string) error {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		for _, key := range keys {
+			for _, offset := range bloomOffsets(key, f.m, f.k) {
+				f.rebuilding[offset/64] |= 1 << (offset % 64)
+			}
+		}
+		return nil
+	})
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err == nil {
+		f.bits = f.rebuilding
+		f.ready = true
+	}
+	f.rebuilding = nil
+	return err
+}
+
+// NewRedisBloomFilter returns a RedisBloomFilter stored in key of the primary redis of cache,
+// sized for expected keys with the false positive rate fpRate.
+func NewRedisBloomFilter(cache *RedisCache, key string, expected uint64, fpRate float64) *RedisBloomFilter {
+	m, k := bloomSize(expected, fpRate)
+	// the hash tag keeps both keys in the same slot, so that the scripts work in cluster mode
+	key = "{" + key + "}"
+	return &RedisBloomFilter{
+		cache:      cache,
+		key:        key,
+		rebuildKey: key + ":rebuild",
+		m:          m,
+		k:          k,
+	}
+}
+
+// AddCtx adds keys into the filter.
+func (f *RedisBloomFilter) AddCtx(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	return bloomAddScript.Run(ctx, f.cache.primary(), []string{f.key, f.rebuildKey}, f.offsets(keys...)...).Err()
+}
+
+// ExistsCtx reports whether key may exist.
+func (f *RedisBloomFilter) ExistsCtx(ctx context.Context, key string) (bool, error) {
+	exists, err := bloomExistsScript.Run(ctx, f.cache.primary(), []string{f.key}, f.offsets(key)...).Int()
+	if err != nil {
+		return false, err
+	}
+	return exists == 1, nil
+}
+
+// RebuildCtx rebuilds the filter with the keys produced by load,
+// keys added by any instance during the rebuild are kept.
+func (f *RedisBloomFilter) RebuildCtx(ctx context.Context, load BloomLoader) error {
+	// the whole rebuild runs on the primary of its start, rebuild again after a switch over
+	client := f.cache.primary()
+	ok, err := client.SetNX(ctx, f.rebuildKey, "", bloomRebuildExpiry).Result()
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("bloom filter %s is being rebuilt", f.key)
+	}
+
+	err = load(ctx, func(keys ...string) error {
+		if len(keys) == 0 {
+			return nil
+		}
+		// only the rebuilding bitmap gets the loaded keys
+		return bloomAddScript.Run(ctx, client, []string{f.rebuildKey, f.rebuildKey}, f.offsets(keys...)...).Err()
+	})
+	if err != nil {
+		_ = client.Del(ctx, f.rebuildKey).Err()
+		return err
+	}
+
+	if err = client.Rename(ctx, f.rebuildKey, f.key).Err(); err != nil {
+		return err
+	}
+	return client.Persist(ctx, f.key).Err()
+}
+
+func (f *RedisBloomFilter) offsets(keys ...string) []interface{} {
+	offsets := make([]interface{}, 0, len(keys)*int(f.k))
+	for _, key := range keys {
+		for _, offset := range bloomOffsets(key, f.m, f.k) {
+			offsets = append(offsets, strconv.FormatUint(offset, 10))
+		}
+	}
+	return offsets
+}
+
+// BloomLoaderFromColumn returns a BloomLoader that scans column of table in batches ordered by column,
+// every value is formatted as prefix + value, which matches the cache keys of generated models.
+func BloomLoaderFromColumn(db *gorm.DB, table, column, prefix string, batchSize int) BloomLoader {
+	if batchSize <= 0 {
+		batchSize = 1000
+	}
+
+	return func(ctx context.Context, add func(keys ...string) error) error {
+		var last interface{}
+		for {
+			var values []interface{}
+			query := db.WithContext(ctx).Table(table).Order(column).Limit(batchSize)
+			if last != nil {
+				query = query.Where(clause.Gt{Column: clause.Column{Name: column}, Value: last})
+			}
+			if err := query.Pluck(column, &values).Error; err != nil {
+				return err
+			}
+			if len(values) == 0 {
+				return nil
+			}
+
+			keys := make([]string, 0, len(values))
+			for i, value := range values {
+				if b, ok := value.([]byte); ok {
+					values[i] = string(b)
+				}
+				keys = append(keys, fmt.Sprintf("%s%v", prefix, values[i]))
+			}
+			if err := add(keys...); err != nil {
+				return err
+			}
+			if len(values) < batchSize {
+				return nil
+			}
+			last = values[len(values)-1]
+		}
+	}
+}
+
+// WithBloomFilter makes CachedConn consult filter before reading keys with one of prefixes,
+// keys that definitely don't exist return ErrNotFound without touching redis or the database.
+// The keys passed to ExecCtx are added into the filter after the exec succeeds.
+func WithBloomFilter(filter BloomFilter, prefixes ...string) ConnOption {
+	return func(cc *CachedConn) {
+		cc.bloom = &bloomChecker{
+			filter:   filter,
+			prefixes: prefixes,
+		}
+	}
+}
+
+func (bc *bloomChecker) covers(key string) bool {
+	for _, prefix := range bc.prefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// rejects reports whether key definitely doesn't exist, errors of the filter are ignored.
+func (bc *bloomChecker) rejects(ctx context.Context, key string) bool {
+	if bc == nil || !bc.covers(key) {
+		return false
+	}
+
+	exists, err := bc.filter.ExistsCtx(ctx, key)
+	if err != nil {
+		logx.WithContext(ctx).Errorf("gormc: bloom filter check of %q failed: %v", key, err)
+		return false
+	}
+	if !exists {
+		metricBloomRejects.Inc()
+	}
+	return !exists
+}
+
+func (bc *bloomChecker) add(ctx context.Context, keys ...string) {
+	if bc == nil {
+		return
+	}
+
+	covered := make([]string, 0, len(keys))
+	for _, key := range keys {
+		if bc.covers(key) {
+			covered = append(covered, key)
+		}
+	}
+	if len(covered) == 0 {
+		return
+	}
+	if err := bc.filter.AddCtx(ctx, covered...); err != nil {
+		logx.WithContext(ctx).Errorf("gormc: failed to add keys into bloom filter: %v", err)
+	}
+}
+
+// bloomSize returns the number of bits and hash functions of a bloom filter.
+func bloomSize(expected uint64, fpRate float64) (uint64, uint64) {
+	if expected == 0 {
+		expected = 1
+	}
+	if fpRate <= 0 || fpRate >= 1 {
+		fpRate = 0.01
+	}
+
+	m := uint64(math.Ceil(-float64(expected) * math.Log(fpRate) / (math.Ln2 * math.Ln2)))
+	k := uint64(math.Round(float64(m) / float64(expected) * math.Ln2))
+	if k == 0 {
+		k = 1
+	}
+	return m, k
+}
+
+// bloomOffsets returns the k bit offsets of key with double hashing, it is stable across instances.
+func bloomOffsets(key string, m, k uint64) []uint64 {
+	h := hash.Hash([]byte(key))
+	h1, h2 := h&math.MaxUint32, h>>32
+	offsets := make([]uint64, k)
+	for i := uint64(0); i < k; i++ {
+		offsets[i] = (h1 + i*h2) % m
+	}
+	return offsets
+}
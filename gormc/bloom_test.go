@@ -0,0 +1,160 @@
+package gormc_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/huof6829/gorm-zero/gormc"
+	"github.com/huof6829/gorm-zero/gormc/batchx"
+	"gorm.io/gorm"
+)
+
+func TestBloomFilter_Rebuild(t *testing.T) {
+	db := setupTestDB(t)
+	_, cache := setupTestCache(t)
+	ctx := context.Background()
+	for i := 1; i <= 25; i++ {
+		db.Create(&TestUser{ID: int64(i), Name: fmt.Sprintf("user%d", i)})
+	}
+	load := gormc.BloomLoaderFromColumn(db, "users", "id", "user:", 10)
+
+	filters := map[string]gormc.BloomFilter{
+		"local": gormc.NewLocalBloomFilter(1000, 0.001),
+		"redis": gormc.NewRedisBloomFilter(cache, "bloom:users", 1000, 0.001),
+	}
+	for name, filter := range filters {
+		t.Run(name, func(t *testing.T) {
+			// 未重建前全部放行
+			if ok, err := filter.ExistsCtx(ctx, "user:100"); err != nil || !ok {
+				t.Fatalf("Expected a filter that was never rebuilt to pass, got %v (%v)", ok, err)
+			}
+
+			if err := filter.RebuildCtx(ctx, load); err != nil {
+				t.Fatalf("RebuildCtx failed: %v", err)
+			}
+			for i := 1; i <= 25; i++ {
+				if ok, err := filter.ExistsCtx(ctx, fmt.Sprintf("user:%d", i)); err != nil || !ok {
+					t.Fatalf("Expected user:%d to exist, got %v (%v)", i, ok, err)
+				}
+			}
+			if ok, _ := filter.ExistsCtx(ctx, "user:100"); ok {
+				t.Error("Expected user:100 to be rejected")
+			}
+
+			if err := filter.AddCtx(ctx, "user:100"); err != nil {
+				t.Fatalf("AddCtx failed: %v", err)
+			}
+			if ok, _ := filter.ExistsCtx(ctx, "user:100"); !ok {
+				t.Error("Expected user:100 to exist after AddCtx")
+			}
+		})
+	}
+}
+
+func TestCachedConn_BloomFilter(t *testing.T) {
+	db := setupTestDB(t)
+	mr, cache := setupTestCache(t)
+	ctx := context.Background()
+	db.Create(&TestUser{ID: 1, Name: "Alice"})
+
+	filter := gormc.NewLocalBloomFilter(1000, 0.001)
+	if err := filter.RebuildCtx(ctx, gormc.BloomLoaderFromColumn(db, "users", "id", "user:", 100)); err != nil {
+		t.Fatalf("RebuildCtx failed: %v", err)
+	}
+	cachedConn := gormc.NewConnWithCache(db, cache, gormc.WithBloomFilter(filter, "user:"))
+
+	queries := 0
+	query := func(id int64, user *TestUser) error {
+		return cachedConn.QueryCtx(ctx, user, fmt.Sprintf("user:%d", id), func(conn *gorm.DB) error {
+			queries++
+			return conn.Where("id = ?", id).First(user).Error
+		})
+	}
+
+	// 不存在的 id 直接返回，不访问 Redis 和数据库
+	var user TestUser
+	if err := query(2, &user); !errors.Is(err, gormc.ErrNotFound) {
+		t.Fatalf("Expected ErrNotFound, got %v", err)
+	}
+	if queries != 0 || mr.Exists("user:2") {
+		t.Errorf("Expected the lookup to be rejected before the cache and the database, queries=%d", queries)
+	}
+
+	if err := query(1, &user); err != nil || user.Name != "Alice" {
+		t.Fatalf("Expected Alice, got %q (%v)", user.Name, err)
+	}
+
+	// 插入后通过 ExecWithKeysCtx 加入过滤器
+	newUser := &TestUser{Name: "Bob"}
+	err := cachedConn.ExecWithKeysCtx(ctx, func(conn *gorm.DB) error {
+		return conn.Create(newUser).Error
+	}, func() []string {
+		return []string{fmt.Sprintf("user:%d", newUser.ID)}
+	})
+	if err != nil {
+		t.Fatalf("ExecWithKeysCtx failed: %v", err)
+	}
+	var bob TestUser
+	if err := query(newUser.ID, &bob); err != nil || bob.Name != "Bob" {
+		t.Fatalf("Expected Bob, got %q (%v)", bob.Name, err)
+	}
+
+	// 不在前缀内的 key 不检查
+	var other TestUser
+	err = cachedConn.QueryCtx(ctx, &other, "other:1", func(conn *gorm.DB) error {
+		return conn.Where("id = ?", 1).First(&other).Error
+	})
+	if err != nil {
+		t.Fatalf("Expected keys out of the prefixes to pass, got %v", err)
+	}
+}
+
+// bloomUserModel 模拟生成的 model，提供 GetCacheKeys
+type bloomUserModel struct {
+	gormc.CachedConn
+}
+
+func (m bloomUserModel) GetCacheKeys(data *TestUser) []string {
+	return []string{fmt.Sprintf("user:%d", data.ID)}
+}
+
+func TestBatchInsertCtx_BloomFilter(t *testing.T) {
+	db := setupTestDB(t)
+	_, cache := setupTestCache(t)
+	ctx := context.Background()
+
+	filter := gormc.NewLocalBloomFilter(1000, 0.001)
+	if err := filter.RebuildCtx(ctx, gormc.BloomLoaderFromColumn(db, "users", "id", "user:", 100)); err != nil {
+		t.Fatalf("RebuildCtx failed: %v", err)
+	}
+	m := bloomUserModel{gormc.NewConnWithCache(db, cache, gormc.WithBloomFilter(filter, "user:"))}
+
+	// 自增 id 在插入后才确定，批量插入的行也要加入过滤器
+	news := []TestUser{{Name: "Alice"}, {Name: "Bob"}}
+	err := batchx.BatchInsertCtx(ctx, m, news, func(db *gorm.DB) error {
+		for i := range news {
+			if err := db.Create(&news[i]).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	}, nil)
+	if err != nil {
+		t.Fatalf("BatchInsertCtx failed: %v", err)
+	}
+
+	for _, row := range news {
+		if row.ID == 0 {
+			t.Fatal("Expected the auto-increment id to be set")
+		}
+		var user TestUser
+		err := m.QueryCtx(ctx, &user, fmt.Sprintf("user:%d", row.ID), func(conn *gorm.DB) error {
+			return conn.Where("id = ?", row.ID).First(&user).Error
+		})
+		if err != nil || user.Name != row.Name {
+			t.Errorf("Expected %s, got %q (%v)", row.Name, user.Name, err)
+		}
+	}
+}
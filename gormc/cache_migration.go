@@ -0,0 +1,162 @@
+package gormc
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+type (
+	// cacheMigration holds the clients of a RedisCache migrating between redis deployments.
+	cacheMigration struct {
+		clients      atomic.Pointer[migrationClients]
+		readFallback bool
+	}
+
+	migrationClients struct {
+		primary   redis.Cmdable
+		secondary redis.Cmdable
+	}
+)
+
+// WithSecondary puts a RedisCache into migration mode with secondary as the other redis deployment.
+//
+// Reads go to the primary, with readFallback the misses are looked up in the secondary and copied into
+// the primary. Writes and deletes are applied to both, so that neither holds stale entries.
+// A typical migration from old to new is:
+//
+//	cache := gormc.NewRedisCacheWithClient(old, expiry, gormc.WithSecondary(new, true))
+//	// dual-write until the new deployment is warm, then
+//	cache.SwitchOver() // new is the primary, misses fall back to old
+//	// and eventually
+//	cache.FinishMigration().(*redis.Client).Close()
+func WithSecondary(secondary redis.Cmdable, readFallback bool) RedisCacheOption {
+	return func(c *RedisCache) {
+		c.migration = &cacheMigration{readFallback: readFallback}
+		c.migration.clients.Store(&migrationClients{
+			primary:   c.client,
+			secondary: secondary,
+		})
+	}
+}
+
+// SwitchOver swaps the primary and the secondary clients, calling it again rolls back.
+// It does nothing if c is not in migration mode.
+func (c *RedisCache) SwitchOver() {
+	if c.migration == nil {
+		return
+	}
+
+	clients := c.migration.clients.Load()
+	if clients.secondary == nil {
+		return
+	}
+	c.migration.clients.Store(&migrationClients{
+		primary:   clients.secondary,
+		secondary: clients.primary,
+	})
+	logx.Info("gormc: cache switched over to the secondary redis")
+}
+
+// FinishMigration stops writing to the secondary and returns it, so that the caller can close it.
+// It returns nil if c is not in migration mode.
+func (c *RedisCache) FinishMigration() redis.Cmdable {
+	if c.migration == nil {
+		return nil
+	}
+
+	clients := c.migration.clients.Load()
+	c.migration.clients.Store(&migrationClients{primary: clients.primary})
+	logx.Info("gormc: cache migration finished")
+	return clients.secondary
+}
+
+// primary returns the client serving reads.
+func (c *RedisCache) primary() redis.Cmdable {
+	if c.migration == nil {
+		return c.client
+	}
+	return c.migration.clients.Load().primary
+}
+
+// secondary returns the client receiving the writes of a migration, nil if not migrating.
+func (c *RedisCache) secondary() redis.Cmdable {
+	if c.migration == nil {
+		return nil
+	}
+	return c.migration.clients.Load().secondary
+}
+
+// get returns the raw value of key from the primary, falls back to the secondary if configured.
+func (c *RedisCache) get(ctx context.Context, key string) ([]byte, error) {
+	primary, secondary := c.primary(), c.secondary()
+	data, err := primary.Get(ctx, key).Bytes()
+	if !errors.Is(err, redis.Nil) || secondary == nil || !c.migration.readFallback {
+		return data, err
+	}
+
+	data, err = secondary.Get(ctx, key).Bytes()
+	if err != nil {
+		if !errors.Is(err, redis.Nil) {
+			metricMigration.Inc("fallback_error")
+			logx.WithContext(ctx).Errorf("gormc: failed to read %q from the secondary redis: %v", key, err)
+		}
+		return nil, redis.Nil
+	}
+
+	metricMigration.Inc("fallback_hit")
+	// copy it with the remaining ttl, so that the primary warms up
+	if ttl, err := secondary.PTTL(ctx, key).Result(); err == nil && ttl > 0 {
+		_ = primary.SetNX(ctx, key, data, ttl).Err()
+	}
+	return data, nil
+}
+
+// set writes the raw value of key into the primary, and into the secondary while migrating.
+func (c *RedisCache) set(ctx context.Context, key string, data []byte, expire time.Duration) error {
+	if err := c.primary().Set(ctx, key, data, expire).Err(); err != nil {
+		return err
+	}
+
+	if secondary := c.secondary(); secondary != nil {
+		if err := secondary.Set(ctx, key, data, expire).Err(); err != nil {
+			metricMigration.Inc("secondary_error")
+			logx.WithContext(ctx).Errorf("gormc: failed to write %q into the secondary redis: %v", key, err)
+			// don't leave the old value behind
+			_ = secondary.Del(ctx, key).Err()
+		}
+	}
+	return nil
+}
+
+// del deletes keys from the primary, and from the secondary while migrating.
+func (c *RedisCache) del(ctx context.Context, keys ...string) error {
+	err := delKeys(ctx, c.primary(), keys)
+	if secondary := c.secondary(); secondary != nil {
+		if serr := delKeys(ctx, secondary, keys); serr != nil {
+			metricMigration.Inc("secondary_error")
+			err = errors.Join(err, serr)
+		}
+	}
+	return err
+}
+
+// delKeys deletes keys one command per key in a pipeline,
+// so that keys of different slots can be deleted together in cluster mode.
+func delKeys(ctx context.Context, client redis.Cmdable, keys []string) error {
+	if len(keys) == 1 {
+		return client.Del(ctx, keys[0]).Err()
+	}
+
+	_, err := client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, key := range keys {
+			pipe.Del(ctx, key)
+		}
+		return nil
+	})
+	return err
+}
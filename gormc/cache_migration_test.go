@@ -0,0 +1,170 @@
+package gormc_test
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/huof6829/gorm-zero/gormc"
+)
+
+func TestRedisCache_Migration(t *testing.T) {
+	newMr := miniredis.RunT(t)
+	secondary, err := gormc.NewRedisClient(gormc.RedisConfig{Addr: newMr.Addr()})
+	if err != nil {
+		t.Fatalf("NewRedisClient failed: %v", err)
+	}
+	oldMr, cache := setupTestCache(t, gormc.WithSecondary(secondary, true))
+	ctx := context.Background()
+
+	// 迁移前已有的数据只在旧库
+	oldMr.Set("user:1", `{"ID":1,"Name":"Alice"}`)
+	oldMr.SetTTL("user:1", time.Minute)
+
+	// 双写
+	if err := cache.SetCtx(ctx, "user:2", TestUser{ID: 2, Name: "Bob"}); err != nil {
+		t.Fatalf("SetCtx failed: %v", err)
+	}
+	if !oldMr.Exists("user:2") || !newMr.Exists("user:2") {
+		t.Error("Expected writes to go to both redis")
+	}
+
+	// 切换后读新库，未命中回退旧库并回填
+	cache.SwitchOver()
+	var user TestUser
+	if err := cache.GetCtx(ctx, "user:1", &user); err != nil || user.Name != "Alice" {
+		t.Fatalf("Expected the fallback to return Alice, got %q (%v)", user.Name, err)
+	}
+	if !newMr.Exists("user:1") || newMr.TTL("user:1") <= 0 {
+		t.Error("Expected the fallback hit to be copied into the new primary with its ttl")
+	}
+
+	// 删除两边都生效，不会从旧库读到脏数据
+	if err := cache.DelCtx(ctx, "user:1", "user:2"); err != nil {
+		t.Fatalf("DelCtx failed: %v", err)
+	}
+	if oldMr.Exists("user:1") || newMr.Exists("user:1") || oldMr.Exists("user:2") || newMr.Exists("user:2") {
+		t.Error("Expected deletes to go to both redis")
+	}
+
+	// 结束迁移后只写新库
+	if cache.FinishMigration() == nil {
+		t.Fatal("Expected the retired client")
+	}
+	_ = cache.SetCtx(ctx, "user:3", TestUser{ID: 3})
+	if oldMr.Exists("user:3") || !newMr.Exists("user:3") {
+		t.Error("Expected writes to go to the new redis only after the migration")
+	}
+}
+
+func TestRedisCache_MigrationPurge(t *testing.T) {
+	newMr := miniredis.RunT(t)
+	secondary, err := gormc.NewRedisClient(gormc.RedisConfig{Addr: newMr.Addr()})
+	if err != nil {
+		t.Fatalf("NewRedisClient failed: %v", err)
+	}
+	oldMr, cache := setupTestCache(t, gormc.WithSecondary(secondary, true))
+	ctx := context.Background()
+	cache.SwitchOver()
+
+	// 只在旧库的 key 也要清理，否则会被回退读到并回填新库
+	oldMr.Set("user:1", `{"ID":1,"Name":"Alice"}`)
+	newMr.Set("user:2", `{"ID":2,"Name":"Bob"}`)
+	oldMr.Set("user:2", `{"ID":2,"Name":"Bob"}`)
+	result, err := cache.PurgeKeysCtx(ctx, gormc.ScanOptions{Pattern: "user:*"})
+	if err != nil {
+		t.Fatalf("PurgeKeysCtx failed: %v", err)
+	}
+	if result.Deleted != 3 {
+		t.Errorf("Expected 3 deleted keys, got %d", result.Deleted)
+	}
+	var user TestUser
+	if err := cache.GetCtx(ctx, "user:1", &user); err == nil {
+		t.Errorf("Expected the purged key to be missing, got %+v", user)
+	}
+	if oldMr.Exists("user:1") || newMr.Exists("user:1") || oldMr.Exists("user:2") {
+		t.Error("Expected the keys purged from both redis")
+	}
+}
+
+func TestCachedConn_MigrationTableVersions(t *testing.T) {
+	db := setupTestDB(t)
+	newMr := miniredis.RunT(t)
+	secondary, _ := gormc.NewRedisClient(gormc.RedisConfig{Addr: newMr.Addr()})
+	oldMr, cache := setupTestCache(t, gormc.WithSecondary(secondary, false))
+	cachedConn := gormc.NewConnWithCache(db, cache, gormc.WithModelName("users"), gormc.WithTableVersions())
+
+	oldMr.Set("gormc:table:version:users", "5")
+	if err := cachedConn.BumpTableVersionsCtx(context.Background(), "users"); err != nil {
+		t.Fatalf("BumpTableVersionsCtx failed: %v", err)
+	}
+	if got, _ := newMr.Get("gormc:table:version:users"); got != "6" {
+		t.Errorf("Expected the version to be copied into the secondary, got %q", got)
+	}
+}
+
+func TestRedisCache_MigrationCounterAndBloom(t *testing.T) {
+	db := setupTestDB(t)
+	if err := db.AutoMigrate(&TestPost{}); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+	db.Create(&TestPost{ID: 1, Views: 10})
+
+	newMr := miniredis.RunT(t)
+	secondary, err := gormc.NewRedisClient(gormc.RedisConfig{Addr: newMr.Addr()})
+	if err != nil {
+		t.Fatalf("NewRedisClient failed: %v", err)
+	}
+	oldMr, cache := setupTestCache(t, gormc.WithSecondary(secondary, true))
+	ctx := context.Background()
+
+	// 迁移前创建的计数器和布隆过滤器
+	counter, err := gormc.NewCounter(db, cache, gormc.CounterConf{
+		Table: "posts", Column: "views", Prefix: "counter:post:views:"})
+	if err != nil {
+		t.Fatalf("NewCounter failed: %v", err)
+	}
+	filter := gormc.NewRedisBloomFilter(cache, "bloom:posts", 1000, 0.001)
+	if _, err := counter.IncrCtx(ctx, 1, 1); err != nil {
+		t.Fatalf("IncrCtx failed: %v", err)
+	}
+	if err := counter.FlushCtx(ctx); err != nil {
+		t.Fatalf("FlushCtx failed: %v", err)
+	}
+
+	// 切换后跟随新的主库
+	cache.SwitchOver()
+	if total, err := counter.IncrCtx(ctx, 1, 1); err != nil || total != 12 {
+		t.Fatalf("Expected 12, got %d (%v)", total, err)
+	}
+	if !newMr.Exists("{counter:post:views:}pending") || oldMr.Exists("{counter:post:views:}pending") {
+		t.Error("Expected the increment to go to the new primary")
+	}
+
+	// 结束迁移并关闭旧客户端后仍可用
+	retired, ok := cache.FinishMigration().(io.Closer)
+	if !ok {
+		t.Fatal("Expected the retired client to be closable")
+	}
+	if err := retired.Close(); err != nil {
+		t.Fatalf("Failed to close the retired client: %v", err)
+	}
+	if err := counter.FlushCtx(ctx); err != nil {
+		t.Fatalf("FlushCtx failed after the migration: %v", err)
+	}
+	var post TestPost
+	db.First(&post, 1)
+	if post.Views != 12 {
+		t.Errorf("Expected 12 views in the database, got %d", post.Views)
+	}
+	if err := filter.RebuildCtx(ctx, func(ctx context.Context, add func(keys ...string) error) error {
+		return add("post:1")
+	}); err != nil {
+		t.Fatalf("RebuildCtx failed after the migration: %v", err)
+	}
+	if !newMr.Exists("{bloom:posts}") || oldMr.Exists("{bloom:posts}") {
+		t.Error("Expected the filter to be rebuilt in the new primary")
+	}
+}
@@ -0,0 +1,138 @@
+package gormc
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+const (
+	// CacheModeNormal reads from and writes to the cache.
+	CacheModeNormal CacheMode = iota
+	// CacheModeBypassReads doesn't read or populate the cache, keys are still invalidated on writes.
+	CacheModeBypassReads
+	// CacheModeWriteOnly doesn't read the cache, but populates it and invalidates keys, useful to warm up.
+	CacheModeWriteOnly
+	// CacheModeDisabled doesn't touch the cache at all, not even to invalidate keys,
+	// so purge the affected keys before switching back. A degraded start replays them itself.
+	CacheModeDisabled
+)
+
+var cacheModeNames = []string{"normal", "bypass-reads", "write-only", "disabled"}
+
+// CacheMode is the runtime mode of a RedisCache, see the CacheMode constants.
+type CacheMode int32
+
+// ParseCacheMode parses the name of a CacheMode.
+func ParseCacheMode(s string) (CacheMode, error) {
+	for i, name := range cacheModeNames {
+		if name == s {
+			return CacheMode(i), nil
+		}
+	}
+	return CacheModeNormal, fmt.Errorf("cache: unknown mode %q", s)
+}
+
+func (m CacheMode) String() string {
+	if m < 0 || int(m) >= len(cacheModeNames) {
+		return fmt.Sprintf("CacheMode(%d)", int32(m))
+	}
+	return cacheModeNames[m]
+}
+
+func (m CacheMode) readable() bool {
+	return m == CacheModeNormal
+}
+
+func (m CacheMode) writable() bool {
+	return m == CacheModeNormal || m == CacheModeWriteOnly
+}
+
+func (m CacheMode) deletable() bool {
+	return m != CacheModeDisabled
+}
+
+// Mode returns the current mode of c.
+func (c *RedisCache) Mode() CacheMode {
+	return CacheMode(c.mode.Load())
+}
+
+// SetMode switches the mode of c at runtime, it affects every CachedConn sharing c.
+func (c *RedisCache) SetMode(mode CacheMode) {
+	c.asyncMu.Lock()
+	old := CacheMode(c.mode.Swap(int32(mode)))
+	if old != mode {
+		c.countModeSwitch(old, mode)
+	}
+	c.asyncMu.Unlock()
+
+	if old != mode {
+		logx.Infof("gormc: cache mode switched from %s to %s", old, mode)
+	}
+}
+
+// switchMode switches c from old to mode if c is still in old.
+func (c *RedisCache) switchMode(old, mode CacheMode) bool {
+	c.asyncMu.Lock()
+	defer c.asyncMu.Unlock()
+
+	if !c.mode.CompareAndSwap(int32(old), int32(mode)) {
+		return false
+	}
+	c.countModeSwitch(old, mode)
+	return true
+}
+
+// countModeSwitch moves c between the mode gauges, c.asyncMu must be held.
+// A closed cache has already left the gauges.
+func (c *RedisCache) countModeSwitch(old, mode CacheMode) {
+	if c.closed {
+		return
+	}
+	metricCacheMode.Dec(old.String())
+	metricCacheMode.Inc(mode.String())
+}
+
+// Mode returns the current cache mode.
+func (cc CachedConn) Mode() CacheMode {
+	return cc.cache.Mode()
+}
+
+// SetMode switches the cache mode at runtime, it affects every CachedConn sharing the same RedisCache.
+func (cc CachedConn) SetMode(mode CacheMode) {
+	cc.cache.SetMode(mode)
+}
+
+type cacheModeResponse struct {
+	Mode string `json:"mode"`
+}
+
+// NewCacheModeHandler returns an http.Handler to inspect and switch the mode of c.
+// GET returns the current mode, POST or PUT with the mode query parameter switches it, e.g.
+//
+//	curl -X POST 'http://localhost:8888/admin/cache/mode?mode=bypass-reads'
+//
+// It doesn't authenticate the callers, protect it with a middleware when mounting it.
+func NewCacheModeHandler(c *RedisCache) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPost, http.MethodPut:
+			mode, err := ParseCacheMode(r.URL.Query().Get("mode"))
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			c.SetMode(mode)
+		default:
+			w.Header().Set("Allow", "GET, POST, PUT")
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(cacheModeResponse{Mode: c.Mode().String()})
+	})
+}
@@ -0,0 +1,145 @@
+package gormc_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/huof6829/gorm-zero/gormc"
+	"github.com/prometheus/client_golang/prometheus"
+	zeroprom "github.com/zeromicro/go-zero/core/prometheus"
+	"gorm.io/gorm"
+)
+
+func TestCachedConn_Modes(t *testing.T) {
+	db, mr, cachedConn := setupTestEnv(t)
+	defer mr.Close()
+	ctx := context.Background()
+	db.Create(&TestUser{ID: 1, Name: "Alice"})
+
+	queries := 0
+	query := func(v *TestUser) error {
+		return cachedConn.QueryCtx(ctx, v, "user:1", func(conn *gorm.DB) error {
+			queries++
+			return conn.Where("id = ?", 1).First(v).Error
+		})
+	}
+
+	var user TestUser
+	if err := query(&user); err != nil {
+		t.Fatalf("QueryCtx failed: %v", err)
+	}
+
+	// bypass-reads: 不读不写缓存，但仍然删除
+	cachedConn.SetMode(gormc.CacheModeBypassReads)
+	mr.Del("user:1")
+	if err := query(&user); err != nil {
+		t.Fatalf("QueryCtx failed: %v", err)
+	}
+	if queries != 2 || mr.Exists("user:1") {
+		t.Errorf("bypass-reads must query db without populating cache, queries=%d", queries)
+	}
+	mr.Set("user:1", "{}")
+	if err := cachedConn.DelCacheCtx(ctx, "user:1"); err != nil || mr.Exists("user:1") {
+		t.Errorf("bypass-reads must still invalidate keys, err: %v", err)
+	}
+
+	// write-only: 不读缓存，但写入缓存
+	cachedConn.SetMode(gormc.CacheModeWriteOnly)
+	if err := query(&user); err != nil {
+		t.Fatalf("QueryCtx failed: %v", err)
+	}
+	if queries != 3 || !mr.Exists("user:1") {
+		t.Errorf("write-only must query db and populate cache, queries=%d", queries)
+	}
+
+	// disabled: 完全不访问缓存
+	cachedConn.SetMode(gormc.CacheModeDisabled)
+	if err := cachedConn.DelCacheCtx(ctx, "user:1"); err != nil || !mr.Exists("user:1") {
+		t.Errorf("disabled must not touch the cache, err: %v", err)
+	}
+
+	cachedConn.SetMode(gormc.CacheModeNormal)
+	if err := query(&user); err != nil || queries != 3 {
+		t.Errorf("normal mode must read from cache, queries=%d err=%v", queries, err)
+	}
+}
+
+func TestCacheModeHandler(t *testing.T) {
+	_, cache := setupTestCache(t)
+	handler := gormc.NewCacheModeHandler(cache)
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/?mode=write-only", nil))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"write-only"`) {
+		t.Fatalf("unexpected response: %d %s", w.Code, w.Body.String())
+	}
+	if cache.Mode() != gormc.CacheModeWriteOnly {
+		t.Errorf("Expected write-only mode, got %s", cache.Mode())
+	}
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/?mode=unknown", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for unknown mode, got %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	if !strings.Contains(w.Body.String(), `"write-only"`) {
+		t.Errorf("unexpected response: %s", w.Body.String())
+	}
+}
+
+// cacheModeGauge 读取 gormc_cache_mode_caches 各模式的值
+func cacheModeGauge(t *testing.T) map[string]float64 {
+	families, err := prometheus.DefaultGatherer.Gather()
+	if err != nil {
+		t.Fatalf("Failed to gather metrics: %v", err)
+	}
+	values := make(map[string]float64)
+	for _, family := range families {
+		if family.GetName() != "gormc_cache_mode_caches" {
+			continue
+		}
+		for _, m := range family.GetMetric() {
+			values[m.GetLabel()[0].GetValue()] = m.GetGauge().GetValue()
+		}
+	}
+	return values
+}
+
+func TestRedisCache_ModeGaugeClose(t *testing.T) {
+	zeroprom.Enable()
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("Failed to start miniredis: %v", err)
+	}
+	defer mr.Close()
+
+	before := cacheModeGauge(t)
+	cache, err := gormc.NewRedisCache(gormc.RedisConfig{Addr: mr.Addr()}, time.Minute)
+	if err != nil {
+		t.Fatalf("Failed to create redis cache: %v", err)
+	}
+	cache.SetMode(gormc.CacheModeWriteOnly)
+	if got := cacheModeGauge(t)["write-only"]; got != before["write-only"]+1 {
+		t.Fatalf("Expected the write-only gauge to count the cache, got %v", got)
+	}
+
+	// 关闭后缓存不再计入任何模式，关闭后切换模式也不计入
+	if err := cache.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	cache.SetMode(gormc.CacheModeNormal)
+	after := cacheModeGauge(t)
+	for _, mode := range []string{"normal", "write-only"} {
+		if after[mode] != before[mode] {
+			t.Errorf("Expected the %s gauge to be %v after Close, got %v", mode, before[mode], after[mode])
+		}
+	}
+}
@@ -0,0 +1,219 @@
+package gormc
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/zeromicro/go-zero/core/collection"
+	"github.com/zeromicro/go-zero/core/conf"
+	"github.com/zeromicro/go-zero/core/logx"
+	"github.com/zeromicro/go-zero/core/threading"
+)
+
+// notFoundPlaceholder is cached for rows that don't exist, it is never a valid json value.
+const notFoundPlaceholder = "*"
+
+type (
+	// CachePolicy is the cache policy of a model or a cache key prefix.
+	CachePolicy struct {
+		Expiry         time.Duration `json:",optional"`   // TTL of cached values, 0 means the expiry of the RedisCache
+		NotFoundExpiry time.Duration `json:",optional"`   // TTL of not found placeholders, 0 means not found results are not cached
+		Disabled       bool          `json:",optional"`   // bypass the cache for reads, writes still invalidate the keys
+		LocalCache     bool          `json:",optional"`   // keep values in the in-process L1 cache
+		LocalExpiry    time.Duration `json:",default=1s"` // TTL of the L1 cache
+		Compress       bool          `json:",optional"`   // gzip the encoded values
+	}
+
+	// CachePolicyConf is the configuration of cache policies.
+	CachePolicyConf struct {
+		// Models is keyed by model name, which is the table name for generated models.
+		Models map[string]CachePolicy `json:",optional"`
+		// Prefixes is keyed by cache key prefix, e.g. "cache:order:", the longest matching prefix wins.
+		Prefixes map[string]CachePolicy `json:",optional"`
+		// LocalCapacity is the max number of values kept in the L1 cache.
+		LocalCapacity int `json:",default=10000"`
+	}
+
+	// CachePolicies is a registry of cache policies, it can be updated at runtime.
+	CachePolicies struct {
+		conf  atomic.Pointer[CachePolicyConf]
+		local *collection.Cache
+		// generation is bumped on Update, L1 values of older generations are ignored.
+		generation atomic.Uint64
+	}
+
+	localValue struct {
+		generation uint64
+		data       []byte
+	}
+)
+
+// NewCachePolicies returns a CachePolicies with given conf.
+func NewCachePolicies(c CachePolicyConf) *CachePolicies {
+	if c.LocalCapacity <= 0 {
+		c.LocalCapacity = 10000
+	}
+
+	p := new(CachePolicies)
+	local, err := collection.NewCache(time.Second, collection.WithLimit(c.LocalCapacity),
+		collection.WithName("gormc-l1"))
+	if err != nil {
+		logx.Errorf("gormc: L1 cache disabled: %v", err)
+	} else {
+		p.local = local
+	}
+	p.Update(c)
+	return p
+}
+
+// WithCachePolicies applies the cache policies to a RedisCache.
+func WithCachePolicies(p *CachePolicies) RedisCacheOption {
+	return func(c *RedisCache) {
+		c.policies = p
+	}
+}
+
+// Update replaces all the policies, it is safe to be called concurrently with cache operations.
+func (p *CachePolicies) Update(c CachePolicyConf) {
+	p.conf.Store(&c)
+	// the L1 settings may have changed, drop everything rather than serving with the old policy
+	p.generation.Add(1)
+}
+
+// Lookup returns the policy of model, or the policy of the longest prefix of key.
+func (p *CachePolicies) Lookup(model, key string) (CachePolicy, bool) {
+	if p == nil {
+		return CachePolicy{}, false
+	}
+
+	c := p.conf.Load()
+	if model != "" {
+		if policy, ok := c.Models[model]; ok {
+			return policy, true
+		}
+	}
+
+	var (
+		policy CachePolicy
+		found  bool
+		length int
+	)
+	for prefix, pp := range c.Prefixes {
+		if len(prefix) > length && strings.HasPrefix(key, prefix) {
+			policy, found, length = pp, true, len(prefix)
+		}
+	}
+	return policy, found
+}
+
+func (p *CachePolicies) getLocal(key string) ([]byte, bool) {
+	if p == nil || p.local == nil {
+		return nil, false
+	}
+	val, ok := p.local.Get(key)
+	if !ok {
+		return nil, false
+	}
+	lv, ok := val.(localValue)
+	if !ok || lv.generation != p.generation.Load() {
+		return nil, false
+	}
+	return lv.data, true
+}
+
+func (p *CachePolicies) setLocal(key string, data []byte, expire time.Duration) {
+	if p == nil || p.local == nil {
+		return
+	}
+	if expire <= 0 {
+		expire = time.Second
+	}
+	p.local.SetWithExpire(key, localValue{generation: p.generation.Load(), data: data}, expire)
+}
+
+func (p *CachePolicies) evict(keys ...string) {
+	if p == nil || p.local == nil {
+		return
+	}
+	for _, key := range keys {
+		p.local.Del(key)
+	}
+}
+
+// WatchCachePolicyFile reloads the CachePolicyConf in file into p when the file is modified.
+// The returned function stops watching.
+func WatchCachePolicyFile(file string, p *CachePolicies, interval time.Duration) (stop func()) {
+	if interval <= 0 {
+		interval = 10 * time.Second
+	}
+
+	var modTime time.Time
+	if info, err := os.Stat(file); err == nil {
+		modTime = info.ModTime()
+	}
+
+	done := make(chan struct{})
+	threading.GoSafe(func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				info, err := os.Stat(file)
+				if err != nil || !info.ModTime().After(modTime) {
+					continue
+				}
+				modTime = info.ModTime()
+
+				var c CachePolicyConf
+				if err := conf.Load(file, &c); err != nil {
+					logx.Errorf("gormc: failed to reload cache policies from %s: %v", file, err)
+					continue
+				}
+				p.Update(c)
+				logx.Infof("gormc: cache policies reloaded from %s", file)
+			}
+		}
+	})
+
+	return func() {
+		close(done)
+	}
+}
+
+var gzipMagic = []byte{0x1f, 0x8b}
+
+func compress(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// decompress returns data as is if it is not gzipped,
+// so that values written before Compress was switched on are still readable.
+func decompress(data []byte) ([]byte, error) {
+	if !bytes.HasPrefix(data, gzipMagic) {
+		return data, nil
+	}
+
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
@@ -0,0 +1,187 @@
+package gormc_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/huof6829/gorm-zero/gormc"
+	"github.com/zeromicro/go-zero/core/conf"
+	"gorm.io/gorm"
+)
+
+// setupPolicyEnv 创建带缓存策略的测试环境
+func setupPolicyEnv(t *testing.T, policyConf gormc.CachePolicyConf) (*gorm.DB, *miniredis.Miniredis, *gormc.CachePolicies, gormc.CachedConn) {
+	db := setupTestDB(t)
+	policies := gormc.NewCachePolicies(policyConf)
+	mr, cache := setupTestCache(t, gormc.WithCachePolicies(policies))
+	return db, mr, policies, gormc.NewConnWithCache(db, cache, gormc.WithModelName("users"))
+}
+
+func TestCachePolicies_Lookup(t *testing.T) {
+	policies := gormc.NewCachePolicies(gormc.CachePolicyConf{
+		Models: map[string]gormc.CachePolicy{"users": {Expiry: time.Hour}},
+		Prefixes: map[string]gormc.CachePolicy{
+			"cache:order:":      {Expiry: time.Minute},
+			"cache:order:item:": {Expiry: time.Second},
+		},
+	})
+
+	tests := []struct {
+		model, key string
+		expiry     time.Duration
+		found      bool
+	}{
+		{model: "users", key: "cache:order:1", expiry: time.Hour, found: true},
+		{key: "cache:order:1", expiry: time.Minute, found: true},
+		{key: "cache:order:item:1", expiry: time.Second, found: true},
+		{key: "cache:product:1"},
+	}
+	for _, tt := range tests {
+		policy, found := policies.Lookup(tt.model, tt.key)
+		if found != tt.found || policy.Expiry != tt.expiry {
+			t.Errorf("Lookup(%q, %q) = %+v, %v", tt.model, tt.key, policy, found)
+		}
+	}
+}
+
+func TestCachePolicies_AppliedByCachedConn(t *testing.T) {
+	db, mr, policies, cachedConn := setupPolicyEnv(t, gormc.CachePolicyConf{
+		Models: map[string]gormc.CachePolicy{
+			"users": {Expiry: 10 * time.Minute, NotFoundExpiry: time.Minute, Compress: true},
+		},
+	})
+	ctx := context.Background()
+	db.Create(&TestUser{ID: 1, Name: "Alice"})
+
+	// 按模型名应用 TTL 和压缩
+	var user TestUser
+	err := cachedConn.QueryCtx(ctx, &user, "user:1", func(conn *gorm.DB) error {
+		return conn.Where("id = ?", 1).First(&user).Error
+	})
+	if err != nil || user.Name != "Alice" {
+		t.Fatalf("QueryCtx failed: %v", err)
+	}
+	if ttl := mr.TTL("user:1"); ttl != 10*time.Minute {
+		t.Errorf("Expected policy TTL 10m, got %v", ttl)
+	}
+	raw, _ := mr.Get("user:1")
+	if !strings.HasPrefix(raw, "\x1f\x8b") {
+		t.Error("Expected gzipped value")
+	}
+	var cached TestUser
+	if err := cachedConn.GetCacheCtx(ctx, "user:1", &cached); err != nil || cached.Name != "Alice" {
+		t.Fatalf("GetCacheCtx failed: %v", err)
+	}
+
+	// 不存在的记录缓存占位符
+	queries := 0
+	for i := 0; i < 2; i++ {
+		err = cachedConn.QueryCtx(ctx, &user, "user:2", func(conn *gorm.DB) error {
+			queries++
+			return conn.Where("id = ?", 2).First(&user).Error
+		})
+		if !errors.Is(err, gormc.ErrNotFound) {
+			t.Fatalf("Expected ErrNotFound, got %v", err)
+		}
+	}
+	if queries != 1 {
+		t.Errorf("Expected not found result to be cached, queried %d times", queries)
+	}
+	if ttl := mr.TTL("user:2"); ttl != time.Minute {
+		t.Errorf("Expected not found TTL 1m, got %v", ttl)
+	}
+
+	// 运行时关闭缓存：读直接走数据库
+	policies.Update(gormc.CachePolicyConf{
+		Models: map[string]gormc.CachePolicy{"users": {Disabled: true}},
+	})
+	db.Model(&TestUser{}).Where("id = ?", 1).Update("name", "Bob")
+	err = cachedConn.QueryCtx(ctx, &user, "user:1", func(conn *gorm.DB) error {
+		return conn.Where("id = ?", 1).First(&user).Error
+	})
+	if err != nil || user.Name != "Bob" {
+		t.Fatalf("Expected cache bypass to read Bob, got %q (%v)", user.Name, err)
+	}
+}
+
+func TestCachePolicies_LocalCache(t *testing.T) {
+	_, mr, _, cachedConn := setupPolicyEnv(t, gormc.CachePolicyConf{
+		Prefixes: map[string]gormc.CachePolicy{"user:": {LocalCache: true, LocalExpiry: time.Minute}},
+	})
+	ctx := context.Background()
+
+	if err := cachedConn.SetCacheCtx(ctx, "user:1", "v1"); err != nil {
+		t.Fatalf("SetCacheCtx failed: %v", err)
+	}
+	var val string
+	if err := cachedConn.GetCacheCtx(ctx, "user:1", &val); err != nil || val != "v1" {
+		t.Fatalf("GetCacheCtx failed: %q (%v)", val, err)
+	}
+
+	// L1 命中，不受 Redis 中值变化影响
+	mr.Set("user:1", `"v2"`)
+	if err := cachedConn.GetCacheCtx(ctx, "user:1", &val); err != nil || val != "v1" {
+		t.Fatalf("Expected L1 value v1, got %q (%v)", val, err)
+	}
+
+	// 删除会清除 L1
+	if err := cachedConn.DelCacheCtx(ctx, "user:1"); err != nil {
+		t.Fatalf("DelCacheCtx failed: %v", err)
+	}
+	if err := cachedConn.GetCacheCtx(ctx, "user:1", &val); !errors.Is(err, gormc.ErrCacheMiss) {
+		t.Fatalf("Expected cache miss after delete, got %v", err)
+	}
+}
+
+func TestWatchCachePolicyFile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "policies.yaml")
+	if err := os.WriteFile(file, []byte("Prefixes:\n  \"user:\":\n    Expiry: 1m\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	var c gormc.CachePolicyConf
+	conf.MustLoad(file, &c)
+	policies := gormc.NewCachePolicies(c)
+	stop := gormc.WatchCachePolicyFile(file, policies, 10*time.Millisecond)
+	defer stop()
+
+	time.Sleep(20 * time.Millisecond)
+	if err := os.WriteFile(file, []byte("Prefixes:\n  \"user:\":\n    Expiry: 2m\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	future := time.Now().Add(time.Second)
+	_ = os.Chtimes(file, future, future)
+
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if policy, _ := policies.Lookup("", "user:1"); policy.Expiry == 2*time.Minute {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("Expected cache policies to be reloaded")
+}
+
+func TestRedisConfig_LoadPolicies(t *testing.T) {
+	var c gormc.RedisConfig
+	err := conf.LoadFromYamlBytes([]byte(`
+Addr: 127.0.0.1:6379
+Policies:
+  Models:
+    orders:
+      Expiry: 30m
+      NotFoundExpiry: 1m
+`), &c)
+	if err != nil {
+		t.Fatalf("LoadFromYamlBytes failed: %v", err)
+	}
+	policy := c.Policies.Models["orders"]
+	if policy.Expiry != 30*time.Minute || policy.NotFoundExpiry != time.Minute || policy.LocalExpiry != time.Second {
+		t.Errorf("unexpected policy: %+v", policy)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"strings"
 	"time"
 
 	"github.com/zeromicro/go-zero/core/mathx"
@@ -42,54 +43,123 @@ type (
 	PrimaryQueryCtxFn func(conn *gorm.DB, v, primary interface{}) error
 	// QueryCtxFn defines the query method.
 	QueryCtxFn func(conn *gorm.DB) error
+	// QueryIntoCtxFn defines the query method that fills v.
+	QueryIntoCtxFn func(conn *gorm.DB, v interface{}) error
 
 	CachedConn struct {
 		db                 *gorm.DB
 		cache              *RedisCache
 		unstableExpiryTime mathx.Unstable
+		model              string
+		verifier           *shadowVerifier
+		bloom              *bloomChecker
+		writeThrough       bool
+		versioned          bool
+		tables             []string
+		sharding           *Sharding
+		namespace          string
 	}
 
+	// ConnOption customizes a CachedConn.
+	ConnOption func(cc *CachedConn)
+
 	Conn struct {
 		db *gorm.DB
 	}
 )
 
 // NewConn returns a CachedConn with a redis cache.
-func NewConn(db *gorm.DB, redisConf RedisConfig, expiry time.Duration) (CachedConn, error) {
+func NewConn(db *gorm.DB, redisConf RedisConfig, expiry time.Duration, opts ...ConnOption) (CachedConn, error) {
 	cache, err := NewRedisCache(redisConf, expiry)
 	if err != nil {
 		return CachedConn{}, err
 	}
-	return NewConnWithCache(db, cache), nil
+	return NewConnWithCache(db, cache, opts...), nil
 }
 
 // NewConnWithCache returns a CachedConn with a custom cache.
-func NewConnWithCache(db *gorm.DB, c *RedisCache) CachedConn {
-	return CachedConn{
+func NewConnWithCache(db *gorm.DB, c *RedisCache, opts ...ConnOption) CachedConn {
+	cc := CachedConn{
 		db:                 db,
 		cache:              c,
 		unstableExpiryTime: mathx.NewUnstable(expiryDeviation),
 	}
+	for _, opt := range opts {
+		opt(&cc)
+	}
+	return cc
+}
+
+// WithModelName sets the model name used to look up the cache policy, generated models use the table name.
+func WithModelName(name string) ConnOption {
+	return func(cc *CachedConn) {
+		cc.model = name
+	}
+}
+
+// WithNamespace prefixes the cache keys and the table versions of the conn with namespace and a colon,
+// so that databases sharing a RedisCache don't collide on tables of the same name.
+// Registry sets it to the name of the database.
+func WithNamespace(namespace string) ConnOption {
+	return func(cc *CachedConn) {
+		if len(namespace) > 0 {
+			cc.namespace = namespace + ":"
+		}
+	}
+}
+
+// CacheKey returns the redis key of key, prefixed with the namespace and suffixed with the shard of ctx.
+// Code working on the redis keys directly, e.g. an admin keyer, must map the keys of the model with it.
+func (cc CachedConn) CacheKey(ctx context.Context, key string) (string, error) {
+	suffix, err := cc.shardSuffix(ctx)
+	if err != nil {
+		return "", err
+	}
+	return cc.namespace + key + suffix, nil
+}
+
+// cacheKeys returns the redis keys of keys, see CacheKey.
+func (cc CachedConn) cacheKeys(ctx context.Context, keys []string) ([]string, error) {
+	if (cc.sharding == nil && len(cc.namespace) == 0) || len(keys) == 0 {
+		return keys, nil
+	}
+	suffix, err := cc.shardSuffix(ctx)
+	if err != nil {
+		return nil, err
+	}
+	cacheKeys := make([]string, len(keys))
+	for i, key := range keys {
+		cacheKeys[i] = cc.namespace + key + suffix
+	}
+	return cacheKeys, nil
 }
 
 // DelCache deletes cache with keys.
 func (cc CachedConn) DelCache(keys ...string) error {
-	return cc.cache.DelCtx(context.Background(), keys...)
+	return cc.DelCacheCtx(context.Background(), keys...)
 }
 
 // DelCacheCtx deletes cache with keys.
 func (cc CachedConn) DelCacheCtx(ctx context.Context, keys ...string) error {
-	return cc.cache.DelCtx(ctx, keys...)
+	cacheKeys, err := cc.cacheKeys(ctx, keys)
+	if err != nil {
+		return err
+	}
+	return cc.cache.DelCtx(ctx, cacheKeys...)
 }
 
 // GetCache unmarshals cache with given key into v.
 func (cc CachedConn) GetCache(key string, v interface{}) error {
-	return cc.cache.GetCtx(context.Background(), key, v)
+	return cc.GetCacheCtx(context.Background(), key, v)
 }
 
 // GetCacheCtx unmarshals cache with given key into v.
 func (cc CachedConn) GetCacheCtx(ctx context.Context, key string, v interface{}) error {
-	return cc.cache.GetCtx(ctx, key, v)
+	key, err := cc.CacheKey(ctx, key)
+	if err != nil {
+		return err
+	}
+	return cc.cache.getCtx(ctx, key, v, cc.policy(key))
 }
 
 // Exec runs given exec on given keys, and returns execution result.
@@ -99,14 +169,38 @@ func (cc CachedConn) Exec(exec ExecCtxFn, keys ...string) error {
 
 // ExecCtx runs given exec on given keys, and returns execution result.
 func (cc CachedConn) ExecCtx(ctx context.Context, execCtx ExecCtxFn, keys ...string) error {
-	err := execCtx(cc.db.WithContext(ctx))
+	// before exec, a write whose keys can't be deleted would leave them stale
+	cacheKeys, err := cc.cacheKeys(ctx, keys)
 	if err != nil {
 		return err
 	}
+	if err := execCtx(cc.db.WithContext(ctx)); err != nil {
+		return err
+	}
+	if err := cc.cache.DelCtx(ctx, cacheKeys...); err != nil {
+		return err
+	}
+	cc.bloom.add(ctx, keys...)
+	return cc.bumpTableVersions(ctx)
+}
+
+// ExecWithKeysCtx runs given exec, then deletes the cache keys returned by keysFn.
+// keysFn is called after exec succeeds, so the keys may depend on values generated by the database,
+// e.g. the auto-increment primary key of an inserted row.
+func (cc CachedConn) ExecWithKeysCtx(ctx context.Context, execCtx ExecCtxFn, keysFn func() []string) error {
+	if _, err := cc.shardSuffix(ctx); err != nil {
+		return err
+	}
+	if err := execCtx(cc.db.WithContext(ctx)); err != nil {
+		return err
+	}
+
+	keys := keysFn()
 	if err := cc.DelCacheCtx(ctx, keys...); err != nil {
 		return err
 	}
-	return nil
+	cc.bloom.add(ctx, keys...)
+	return cc.bumpTableVersions(ctx)
 }
 
 // ExecNoCache runs exec with given sql statement, without affecting cache.
@@ -130,7 +224,7 @@ func (cc CachedConn) ExecNoCache(exec ExecCtxFn) error {
 
 // ExecNoCacheCtx runs exec with given sql statement, without affecting cache.
 func (cc CachedConn) ExecNoCacheCtx(ctx context.Context, execCtx ExecCtxFn) (err error) {
-	ctx, span := startSpan(ctx, "ExecNoCache")
+	ctx, span := cc.startSpan(ctx, "ExecNoCache")
 	defer func() {
 		endSpan(span, err)
 	}()
@@ -145,46 +239,105 @@ func (cc CachedConn) QueryRowIndex(v interface{}, key string, keyer func(primary
 
 // QueryRowIndexCtx unmarshals into v with given key.
 func (cc CachedConn) QueryRowIndexCtx(ctx context.Context, v interface{}, key string, keyer func(primary interface{}) string, indexQuery IndexQueryCtxFn, primaryQuery PrimaryQueryCtxFn) (err error) {
-	ctx, span := startSpan(ctx, "QueryRowIndex")
+	ctx, span := cc.startSpan(ctx, "QueryRowIndex")
 	defer func() {
 		endSpan(span, err)
 	}()
+	ctx = WithPrimary(ctx)
+
+	if cc.bloom.rejects(ctx, key) {
+		return ErrNotFound
+	}
+	if key, err = cc.CacheKey(ctx, key); err != nil {
+		return err
+	}
+	if cc.sharding != nil || len(cc.namespace) > 0 {
+		indexKeyer := keyer
+		keyer = func(primary interface{}) string {
+			// ctx is checked above
+			primaryKey, _ := cc.CacheKey(ctx, indexKeyer(primary))
+			return primaryKey
+		}
+	}
 
 	var primaryKey interface{}
 	var found bool
 
+	policy := cc.policy(key)
+	expiry := cc.cache.expiryOf(policy)
 	queryFunc := func(val interface{}) error {
 		primaryKey, err = indexQuery(cc.db.WithContext(ctx), v)
 		if err != nil {
 			return err
 		}
 		found = true
-		return cc.cache.SetWithExpireCtx(ctx, keyer(primaryKey), v, cc.cache.expiry+cacheSafeGapBetweenIndexAndPrimary)
+		primaryCacheKey := keyer(primaryKey)
+		return cc.cache.setCtx(ctx, primaryCacheKey, v, expiry+cacheSafeGapBetweenIndexAndPrimary, cc.policy(primaryCacheKey))
 	}
 
-	if err = cc.cache.TakeWithExpireCtx(ctx, &primaryKey, key, queryFunc, cc.cache.expiry); err != nil {
+	if err = cc.cache.takeCtx(ctx, &primaryKey, key, queryFunc, expiry, policy); err != nil {
 		return err
 	}
 	if found {
 		return nil
 	}
-	return cc.cache.TakeCtx(ctx, v, keyer(primaryKey), func(v interface{}) error {
+	primaryCacheKey := keyer(primaryKey)
+	hit, err := cc.take(ctx, v, primaryCacheKey, func(v interface{}) error {
 		return primaryQuery(cc.db.WithContext(ctx), v, primaryKey)
 	})
+	if hit && cc.verifier.sample() {
+		cc.verifier.verifyPrimary(ctx, cc, v, primaryKey, primaryQuery, primaryCacheKey, key)
+	}
+	return err
 }
 
 func (cc CachedConn) QueryCtx(ctx context.Context, v interface{}, key string, query QueryCtxFn) (err error) {
-	ctx, span := startSpan(ctx, "Query")
+	ctx, span := cc.startSpan(ctx, "Query")
 	defer func() {
 		endSpan(span, err)
 	}()
-	return cc.cache.TakeCtx(ctx, v, key, func(v interface{}) error {
+	ctx = WithPrimary(ctx)
+	if cc.bloom.rejects(ctx, key) {
+		return ErrNotFound
+	}
+	if key, err = cc.CacheKey(ctx, key); err != nil {
+		return err
+	}
+	hit, err := cc.take(ctx, v, key, func(v interface{}) error {
 		return query(cc.db.WithContext(ctx))
 	})
+	if hit && cc.verifier.sample() {
+		// query writes into variables owned by the caller, it can't be re-run in the background
+		cc.verifier.report(ctx, cc.cache, shadowUnverified, key)
+	}
+	return err
+}
+
+// QueryIntoCtx unmarshals into v with given key and query func, query fills the value it is given.
+// Unlike QueryCtx, the cache hits can be verified by WithShadowVerify, as query can fill a fresh value.
+func (cc CachedConn) QueryIntoCtx(ctx context.Context, v interface{}, key string, query QueryIntoCtxFn) (err error) {
+	ctx, span := cc.startSpan(ctx, "QueryInto")
+	defer func() {
+		endSpan(span, err)
+	}()
+	ctx = WithPrimary(ctx)
+	if cc.bloom.rejects(ctx, key) {
+		return ErrNotFound
+	}
+	if key, err = cc.CacheKey(ctx, key); err != nil {
+		return err
+	}
+	hit, err := cc.take(ctx, v, key, func(v interface{}) error {
+		return query(cc.db.WithContext(ctx), v)
+	})
+	if hit && cc.verifier.sample() {
+		cc.verifier.verify(ctx, cc, v, query, key)
+	}
+	return err
 }
 
 func (cc CachedConn) QueryNoCacheCtx(ctx context.Context, query QueryCtxFn) (err error) {
-	ctx, span := startSpan(ctx, "QueryNoCache")
+	ctx, span := cc.startSpan(ctx, "QueryNoCache")
 	defer func() {
 		endSpan(span, err)
 	}()
@@ -193,54 +346,88 @@ func (cc CachedConn) QueryNoCacheCtx(ctx context.Context, query QueryCtxFn) (err
 
 // QueryWithExpireCtx unmarshals into v with given key, set expire duration and query func.
 func (cc CachedConn) QueryWithExpireCtx(ctx context.Context, v interface{}, key string, expire time.Duration, query QueryCtxFn) (err error) {
-	ctx, span := startSpan(ctx, "QueryWithExpire")
+	ctx, span := cc.startSpan(ctx, "QueryWithExpire")
 	defer func() {
 		endSpan(span, err)
 	}()
-	err = cc.cache.TakeCtx(ctx, v, key, func(v interface{}) error {
+	ctx = WithPrimary(ctx)
+	if key, err = cc.CacheKey(ctx, key); err != nil {
+		return err
+	}
+	_, err = cc.take(ctx, v, key, func(v interface{}) error {
 		return query(cc.db.WithContext(ctx))
 	})
 	if err != nil {
 		return err
 	}
-	return cc.cache.SetWithExpireCtx(ctx, key, v, cc.aroundDuration(expire))
+	return cc.cache.setCtx(ctx, key, v, cc.aroundDuration(expire), cc.policy(key))
 }
 
 // QueryWithCallbackExpireCtx unmarshals into v with given key, set expire duration from callback and query func.
 func (cc CachedConn) QueryWithCallbackExpireCtx(ctx context.Context, v interface{}, key string, query QueryCtxFn, callback func(interface{}) time.Duration) (err error) {
-	ctx, span := startSpan(ctx, "QueryWithCallbackExpire")
+	ctx, span := cc.startSpan(ctx, "QueryWithCallbackExpire")
 	defer func() {
 		endSpan(span, err)
 	}()
-	err = cc.cache.TakeCtx(ctx, v, key, func(v interface{}) error {
+	ctx = WithPrimary(ctx)
+	if callback == nil {
+		return cc.QueryCtx(ctx, v, key, query)
+	}
+	if key, err = cc.CacheKey(ctx, key); err != nil {
+		return err
+	}
+	_, err = cc.take(ctx, v, key, func(v interface{}) error {
 		return query(cc.db.WithContext(ctx))
 	})
 	if err != nil {
 		return err
 	}
-	if callback == nil {
-		return cc.QueryCtx(ctx, v, key, query)
-	}
-	return cc.cache.SetWithExpireCtx(ctx, key, v, callback(v))
+	return cc.cache.setCtx(ctx, key, v, callback(v), cc.policy(key))
 }
 
 func (cc CachedConn) aroundDuration(duration time.Duration) time.Duration {
 	return cc.unstableExpiryTime.AroundDuration(duration)
 }
 
+// policy returns the cache policy of the model of cc or key.
+func (cc CachedConn) policy(key string) CachePolicy {
+	// the policy prefixes are configured without the namespace
+	return cc.cache.lookupPolicy(cc.model, strings.TrimPrefix(key, cc.namespace))
+}
+
+// take is TakeCtx with the cache policy of cc applied, it reports whether v was served from the cache.
+func (cc CachedConn) take(ctx context.Context, v interface{}, key string, query func(v interface{}) error) (bool, error) {
+	policy := cc.policy(key)
+	hit := true
+	err := cc.cache.takeCtx(ctx, v, key, func(v interface{}) error {
+		hit = false
+		return query(v)
+	}, cc.cache.expiryOf(policy), policy)
+	return hit && err == nil, err
+}
+
 // SetCache sets v into cache with given key.
 func (cc CachedConn) SetCache(key string, v interface{}) error {
-	return cc.cache.SetCtx(context.Background(), key, v)
+	return cc.SetCacheCtx(context.Background(), key, v)
 }
 
 // SetCacheCtx sets v into cache with given key.
 func (cc CachedConn) SetCacheCtx(ctx context.Context, key string, val interface{}) error {
-	return cc.cache.SetCtx(ctx, key, val)
+	key, err := cc.CacheKey(ctx, key)
+	if err != nil {
+		return err
+	}
+	policy := cc.policy(key)
+	return cc.cache.setCtx(ctx, key, val, cc.cache.expiryOf(policy), policy)
 }
 
 // SetCacheWithExpireCtx sets v into cache with given key.
 func (cc CachedConn) SetCacheWithExpireCtx(ctx context.Context, key string, val interface{}, expire time.Duration) error {
-	return cc.cache.SetWithExpireCtx(ctx, key, val, expire)
+	key, err := cc.CacheKey(ctx, key)
+	if err != nil {
+		return err
+	}
+	return cc.cache.setCtx(ctx, key, val, expire, cc.policy(key))
 }
 
 // Transact runs given fn in transaction mode.
@@ -253,7 +440,17 @@ func (cc CachedConn) TransactCtx(ctx context.Context, fn func(db *gorm.DB) error
 	return cc.db.WithContext(ctx).Transaction(fn, opts...)
 }
 
-var sqlAttributeKey = attribute.Key("sql.method")
+var (
+	sqlAttributeKey       = attribute.Key("sql.method")
+	cacheModeAttributeKey = attribute.Key("cache.mode")
+)
+
+// startSpan starts a span with the cache mode of cc.
+func (cc CachedConn) startSpan(ctx context.Context, method string) (context.Context, oteltrace.Span) {
+	ctx, span := startSpan(ctx, method)
+	span.SetAttributes(cacheModeAttributeKey.String(cc.Mode().String()))
+	return ctx, span
+}
 
 func startSpan(ctx context.Context, method string) (context.Context, oteltrace.Span) {
 	tracer := otel.Tracer(traceName)
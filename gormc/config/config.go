@@ -1,8 +1,12 @@
 package config
 
 import (
+	"fmt"
 	"log"
+	"net"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/huof6829/gorm-zero/gormc/logger"
@@ -54,3 +58,19 @@ func OverwriteGormLogMode(mode string) gormLogger.LogLevel {
 		return gormLogger.Info
 	}
 }
+
+// SplitReplicaAddr splits a replica address of host or host:port, the port defaults to defaultPort.
+func SplitReplicaAddr(addr string, defaultPort int) (string, int, error) {
+	if !strings.Contains(addr, ":") {
+		return addr, defaultPort, nil
+	}
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return "", 0, err
+	}
+	p, err := strconv.Atoi(port)
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid port of replica %q: %w", addr, err)
+	}
+	return host, p, nil
+}
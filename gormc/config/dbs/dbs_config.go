@@ -0,0 +1,68 @@
+package dbs
+
+import (
+	"fmt"
+
+	"github.com/huof6829/gorm-zero/gormc"
+	"github.com/huof6829/gorm-zero/gormc/config"
+	"github.com/huof6829/gorm-zero/gormc/config/mysql"
+	"github.com/huof6829/gorm-zero/gormc/config/pg"
+	"github.com/huof6829/gorm-zero/gormc/config/sqlite"
+	"gorm.io/gorm"
+)
+
+// Conf describes the databases of a service by name, the names must be unique across the drivers.
+type Conf struct {
+	Mysql  map[string]mysql.Mysql   `json:",optional"`
+	PgSql  map[string]pg.PgSql      `json:",optional"`
+	Sqlite map[string]sqlite.Sqlite `json:",optional"`
+}
+
+// Open opens all the databases of c with opts into a gormc.Registry, whose CachedConn share cache if not nil.
+// If any of them fails, the ones already opened are closed, but not cache.
+func Open(c Conf, cache *gormc.RedisCache, opts ...config.Option) (*gormc.Registry, error) {
+	openers := make(map[string]func() (*gorm.DB, error))
+	add := func(name string, open func() (*gorm.DB, error)) error {
+		if _, ok := openers[name]; ok {
+			return fmt.Errorf("database %q is configured more than once", name)
+		}
+		openers[name] = open
+		return nil
+	}
+	for name, m := range c.Mysql {
+		if err := add(name, func() (*gorm.DB, error) { return mysql.Open(m, opts...) }); err != nil {
+			return nil, err
+		}
+	}
+	for name, m := range c.PgSql {
+		if err := add(name, func() (*gorm.DB, error) { return pg.Open(m, opts...) }); err != nil {
+			return nil, err
+		}
+	}
+	for name, m := range c.Sqlite {
+		if err := add(name, func() (*gorm.DB, error) { return sqlite.Open(m, opts...) }); err != nil {
+			return nil, err
+		}
+	}
+
+	// without the cache, so that closing it on failure leaves the cache alone
+	opened := gormc.NewRegistry(nil)
+	dbs := make(map[string]*gorm.DB, len(openers))
+	for name, open := range openers {
+		db, err := open()
+		if err != nil {
+			_ = opened.Close()
+			return nil, fmt.Errorf("open database %q: %w", name, err)
+		}
+		_ = opened.Add(name, db)
+		dbs[name] = db
+	}
+
+	r := gormc.NewRegistry(cache)
+	for name, db := range dbs {
+		if err := r.Add(name, db); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
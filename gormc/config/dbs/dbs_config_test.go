@@ -0,0 +1,57 @@
+package dbs
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/huof6829/gorm-zero/gormc/config/sqlite"
+	"github.com/zeromicro/go-zero/core/conf"
+)
+
+func TestOpen(t *testing.T) {
+	dir := t.TempDir()
+	yaml := `
+Sqlite:
+  orders:
+    Path: ` + filepath.Join(dir, "orders.db") + `
+    LogMode: silent
+  local:
+    LogMode: silent
+`
+	var c Conf
+	if err := conf.LoadFromYamlBytes([]byte(yaml), &c); err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	// map 里的配置也要填充默认值
+	if !c.Sqlite["orders"].WAL || c.Sqlite["local"].Path != ":memory:" {
+		t.Errorf("Expected the defaults to be filled, got %+v", c.Sqlite)
+	}
+
+	r, err := Open(c, nil)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer r.Close()
+	if names := r.Names(); len(names) != 2 || names[0] != "local" || names[1] != "orders" {
+		t.Errorf("unexpected names: %v", names)
+	}
+	if err := r.Ping(context.Background()); err != nil {
+		t.Errorf("Expected healthy, got %v", err)
+	}
+	// 没有缓存时没有 CachedConn
+	if _, ok := r.Conn("orders"); ok {
+		t.Error("Expected no CachedConn without a cache")
+	}
+}
+
+func TestOpen_Failure(t *testing.T) {
+	// 打不开的库会让整体失败
+	c := Conf{Sqlite: map[string]sqlite.Sqlite{
+		"bad": {Path: filepath.Join(t.TempDir(), "missing", "bad.db"), LogMode: "silent"},
+	}}
+	if _, err := Open(c, nil); err == nil || !strings.Contains(err.Error(), `"bad"`) {
+		t.Errorf("Expected the bad database to fail, got %v", err)
+	}
+}
@@ -1,12 +1,25 @@
 package mysql
 
 import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"database/sql"
 	"errors"
 	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
 	"time"
 
+	gomysql "github.com/go-sql-driver/mysql"
+	"github.com/huof6829/gorm-zero/gormc"
 	"github.com/huof6829/gorm-zero/gormc/config"
 	"github.com/huof6829/gorm-zero/gormc/plugins"
+	"github.com/zeromicro/go-zero/core/logx"
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
@@ -14,8 +27,8 @@ import (
 
 type Mysql struct {
 	Path          string // 服务器地址
-	Port          int    `json:",default=3306"`                                               // 端口
-	Config        string `json:",default=charset%3Dutf8mb4%26parseTime%3Dtrue%26loc%3DLocal"` // 高级配置
+	Port          int    `json:",default=3306"` // 端口
+	Config        string `json:",optional"`     // 高级配置，DSN 参数，覆盖下面的结构化配置
 	Dbname        string // 数据库名
 	Username      string // 数据库用户名
 	Password      string // 数据库密码
@@ -24,10 +37,162 @@ type Mysql struct {
 	LogMode       string `json:",default=dev,options=dev|test|prod|silent"`
 	LogColorful   bool   `json:",default=false"` // 是否开启日志高亮
 	SlowThreshold int64  `json:",default=1000"`
+
+	ConnMaxLifetime time.Duration `json:",optional"` // 连接最大存活时间，0 为不限制，应小于中间代理的超时
+	ConnMaxIdleTime time.Duration `json:",optional"` // 连接最大空闲时间，0 为不限制
+
+	Charset           string        `json:",default=utf8mb4"` // 字符集
+	Collation         string        `json:",optional"`        // 排序规则，如 utf8mb4_unicode_ci
+	ParseTime         bool          `json:",default=true"`    // 是否解析 DATE 和 DATETIME 为 time.Time
+	Loc               string        `json:",default=Local"`   // time.Time 的时区，如 Local、UTC、Asia/Shanghai
+	Timeout           time.Duration `json:",optional"`        // 建立连接超时
+	ReadTimeout       time.Duration `json:",optional"`        // 读超时
+	WriteTimeout      time.Duration `json:",optional"`        // 写超时
+	InterpolateParams bool          `json:",optional"`        // 在客户端拼接参数，省去预处理语句的往返
+
+	// TLS 模式，custom 使用下面的证书，配置了证书时默认为 custom
+	TlsMode       string `json:",default=false,options=false|true|skip-verify|preferred|custom"`
+	TlsCA         string `json:",optional"` // CA 证书路径
+	TlsCert       string `json:",optional"` // 客户端证书路径
+	TlsKey        string `json:",optional"` // 客户端私钥路径
+	TlsServerName string `json:",optional"` // 校验的服务器名，默认为地址
+
+	Replicas             []string      `json:",optional"`                                  // 只读副本地址 host:port，与主库共用账号和库名
+	ReplicaPolicy        string        `json:",default=random,options=random|round-robin"` // 副本负载均衡策略
+	ReplicaCheckInterval time.Duration `json:",default=10s"`                               // 副本健康检查间隔
+
+	Retry gormc.RetryConf `json:",optional"` // 启动时连接重试
+
+	// Credentials reads the username and password of each new connection instead of Username and Password,
+	// so that rotated secrets are picked up without restart, set ConnMaxLifetime to drain the old connections.
+	Credentials gormc.CredentialConf `json:",optional"`
 }
 
+// Dsn returns the DSN of m, it is empty if the Loc or the TLS certificates of m are invalid,
+// the error is logged, use DsnE to handle it.
 func (m *Mysql) Dsn() string {
-	return m.Username + ":" + m.Password + "@tcp(" + m.Path + ":" + fmt.Sprintf("%d", m.Port) + ")/" + m.Dbname + "?" + m.Config
+	dsn, err := m.DsnE()
+	if err != nil {
+		logx.Errorf("gormc: invalid mysql config of %s: %v", m.Path, err)
+	}
+	return dsn
+}
+
+// DsnE returns the DSN of m, or the error if the Loc or the TLS certificates of m are invalid.
+func (m *Mysql) DsnE() (string, error) {
+	return m.dsn(m.Path, m.Port)
+}
+
+// dsn builds the DSN of the server at path:port with the driver, the params of Config take precedence.
+func (m *Mysql) dsn(path string, port int) (string, error) {
+	cfg := gomysql.NewConfig()
+	cfg.User = m.Username
+	cfg.Passwd = m.Password
+	cfg.Net = "tcp"
+	cfg.Addr = net.JoinHostPort(path, strconv.Itoa(port))
+	cfg.DBName = m.Dbname
+	cfg.Collation = m.Collation
+	cfg.ParseTime = m.ParseTime
+	cfg.Timeout = m.Timeout
+	cfg.ReadTimeout = m.ReadTimeout
+	cfg.WriteTimeout = m.WriteTimeout
+	cfg.InterpolateParams = m.InterpolateParams
+	if len(m.Loc) > 0 {
+		loc, err := time.LoadLocation(m.Loc)
+		if err != nil {
+			return "", err
+		}
+		cfg.Loc = loc
+	}
+	tlsConfig, err := m.registerTLS(path, port)
+	if err != nil {
+		return "", err
+	}
+	cfg.TLSConfig = tlsConfig
+
+	var params []string
+	if len(m.Charset) > 0 {
+		params = append(params, "charset="+url.QueryEscape(m.Charset))
+	}
+	if len(m.Config) > 0 {
+		params = append(params, overrideParams(m.Config))
+	}
+
+	dsn := cfg.FormatDSN()
+	if len(params) == 0 {
+		return dsn, nil
+	}
+	if strings.Contains(dsn, "?") {
+		return dsn + "&" + strings.Join(params, "&"), nil
+	}
+	return dsn + "?" + strings.Join(params, "&"), nil
+}
+
+// overrideParams returns the params of Config, which may be URL-encoded as a whole like the former default
+// charset%3Dutf8mb4%26parseTime%3Dtrue%26loc%3DLocal.
+func overrideParams(params string) string {
+	if !strings.Contains(params, "=") {
+		if unescaped, err := url.QueryUnescape(params); err == nil {
+			return unescaped
+		}
+	}
+	return params
+}
+
+// registerTLS registers the TLS config with the certificates of m for the server at path:port,
+// and returns its name for the DSN. The name includes a hash of the server name and the certificates,
+// so that configs of the same server with other certificates don't replace each other.
+func (m *Mysql) registerTLS(path string, port int) (string, error) {
+	hasCerts := len(m.TlsCA) > 0 || len(m.TlsCert) > 0
+	switch {
+	case m.TlsMode == "custom", hasCerts && (m.TlsMode == "" || m.TlsMode == "false"):
+	case m.TlsMode == "false":
+		return "", nil
+	default:
+		return m.TlsMode, nil
+	}
+
+	tlsConfig := &tls.Config{ServerName: path}
+	if len(m.TlsServerName) > 0 {
+		tlsConfig.ServerName = m.TlsServerName
+	}
+	hash := sha256.New()
+	hash.Write([]byte(tlsConfig.ServerName))
+	if len(m.TlsCA) > 0 {
+		pem, err := os.ReadFile(m.TlsCA)
+		if err != nil {
+			return "", err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return "", fmt.Errorf("no certificates in %s", m.TlsCA)
+		}
+		tlsConfig.RootCAs = pool
+		hash.Write(pem)
+	}
+	if len(m.TlsCert) > 0 {
+		certPEM, err := os.ReadFile(m.TlsCert)
+		if err != nil {
+			return "", err
+		}
+		keyPEM, err := os.ReadFile(m.TlsKey)
+		if err != nil {
+			return "", err
+		}
+		cert, err := tls.X509KeyPair(certPEM, keyPEM)
+		if err != nil {
+			return "", err
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+		hash.Write(certPEM)
+		hash.Write(keyPEM)
+	}
+
+	name := fmt.Sprintf("gormc-%s-%d-%x", path, port, hash.Sum(nil)[:8])
+	if err := gomysql.RegisterTLSConfig(name, tlsConfig); err != nil {
+		return "", err
+	}
+	return name, nil
 }
 
 func (m *Mysql) GetGormLogMode() logger.LogLevel {
@@ -41,47 +206,129 @@ func (m *Mysql) GetColorful() bool {
 	return m.LogColorful
 }
 
-func Connect(m Mysql) (*gorm.DB, error) {
+// Open opens the database of m, with the tracing plugin, the logger built from m and the pool of m by default.
+func Open(m Mysql, opts ...config.Option) (*gorm.DB, error) {
 	if m.Dbname == "" {
 		return nil, errors.New("database name is empty")
 	}
-	mysqlCfg := mysql.Config{
-		DSN: m.Dsn(),
+	o := config.NewOptions(opts...)
+	credentials, err := o.CredentialProvider(m.Credentials)
+	if err != nil {
+		return nil, err
 	}
-	newLogger := config.NewDefaultGormLogger(&m)
-	db, err := gorm.Open(mysql.New(mysqlCfg), &gorm.Config{
-		Logger: newLogger,
+	var degraded bool
+	db, err := config.OpenWithRetry("mysql "+m.Path, m.Retry, func(d bool) (*gorm.DB, error) {
+		degraded = d
+		cfg := o.Config(&m)
+		cfg.DisableAutomaticPing = cfg.DisableAutomaticPing || degraded
+		dialector, err := m.dialector(m.Path, m.Port, degraded, credentials)
+		if err != nil {
+			return nil, err
+		}
+		db, err := gorm.Open(dialector, cfg)
+		if err != nil && dialector.Conn != nil {
+			// the connector pool is opened by us, gorm doesn't close it on failure
+			_ = dialector.Conn.(*sql.DB).Close()
+		}
+		return db, err
 	})
 	if err != nil {
 		return nil, err
-	} else {
-		sqldb, _ := db.DB()
-		sqldb.SetMaxIdleConns(m.MaxIdleConns)
-		sqldb.SetMaxOpenConns(m.MaxOpenConns)
-		return db, nil
 	}
+
+	if err = o.Use(db); err != nil {
+		return nil, err
+	}
+	if err = useReplicas(db, m, degraded, credentials); err != nil {
+		return nil, err
+	}
+	if err = o.SetPool(db, m.pool()); err != nil {
+		return nil, err
+	}
+	return db, nil
+}
+
+// Connect opens the database of m, it is Open without options.
+func Connect(m Mysql) (*gorm.DB, error) {
+	return Open(m)
 }
 
+// ConnectWithConfig opens the database of m with cfg, it is Open with config.WithGormConfig.
 func ConnectWithConfig(m Mysql, cfg *gorm.Config) (*gorm.DB, error) {
-	if m.Dbname == "" {
-		return nil, errors.New("database name is empty")
+	return Open(m, config.WithGormConfig(cfg))
+}
+
+func useReplicas(db *gorm.DB, m Mysql, degraded bool, credentials gormc.CredentialProvider) error {
+	if len(m.Replicas) == 0 {
+		return nil
 	}
-	mysqlCfg := mysql.Config{
-		DSN: m.Dsn(),
+
+	replicas := make([]gorm.Dialector, 0, len(m.Replicas))
+	for _, addr := range m.Replicas {
+		host, port, err := config.SplitReplicaAddr(addr, m.Port)
+		if err != nil {
+			return err
+		}
+		dialector, err := m.dialector(host, port, degraded, credentials)
+		if err != nil {
+			return err
+		}
+		replicas = append(replicas, dialector)
 	}
-	db, err := gorm.Open(mysql.New(mysqlCfg), cfg)
+	return db.Use(plugins.NewReplicaPlugin(replicas, plugins.ReplicaConf{
+		Policy:          m.ReplicaPolicy,
+		CheckInterval:   m.ReplicaCheckInterval,
+		MaxIdleConns:    m.MaxIdleConns,
+		MaxOpenConns:    m.MaxOpenConns,
+		ConnMaxLifetime: m.ConnMaxLifetime,
+		ConnMaxIdleTime: m.ConnMaxIdleTime,
+	}))
+}
+
+// dialector returns the dialector of the server at path:port, the version isn't queried if degraded.
+// With credentials, the connections are opened by a connector taking the credentials of each new connection.
+func (m *Mysql) dialector(path string, port int, degraded bool, credentials gormc.CredentialProvider) (*mysql.Dialector, error) {
+	dsn, err := m.dsn(path, port)
 	if err != nil {
 		return nil, err
 	}
+	mysqlCfg := &mysql.Config{
+		DSN:                       dsn,
+		SkipInitializeWithVersion: degraded, // the version can't be queried
+	}
+	if credentials == nil {
+		return &mysql.Dialector{Config: mysqlCfg}, nil
+	}
 
-	err = plugins.InitPlugins(db)
+	driverCfg, err := gomysql.ParseDSN(dsn)
 	if err != nil {
 		return nil, err
 	}
+	err = driverCfg.Apply(gomysql.BeforeConnect(func(ctx context.Context, cfg *gomysql.Config) error {
+		creds, err := gormc.ResolveCredentials(ctx, credentials, m.Username)
+		if err != nil {
+			return err
+		}
+		cfg.User, cfg.Passwd = creds.Username, creds.Password
+		return nil
+	}))
+	if err != nil {
+		return nil, err
+	}
+	connector, err := gomysql.NewConnector(driverCfg)
+	if err != nil {
+		return nil, err
+	}
+	mysqlCfg.DSNConfig = driverCfg
+	mysqlCfg.Conn = sql.OpenDB(connector)
+	return &mysql.Dialector{Config: mysqlCfg}, nil
+}
 
-	sqldb, _ := db.DB()
-	sqldb.SetMaxIdleConns(m.MaxIdleConns)
-	sqldb.SetMaxOpenConns(m.MaxOpenConns)
-	return db, nil
-
+func (m *Mysql) pool() config.Pool {
+	return config.Pool{
+		MaxIdleConns:    m.MaxIdleConns,
+		MaxOpenConns:    m.MaxOpenConns,
+		ConnMaxLifetime: m.ConnMaxLifetime,
+		ConnMaxIdleTime: m.ConnMaxIdleTime,
+	}
 }
@@ -0,0 +1,207 @@
+package mysql
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	gomysql "github.com/go-sql-driver/mysql"
+	"github.com/huof6829/gorm-zero/gormc"
+	"github.com/huof6829/gorm-zero/gormc/config"
+)
+
+func TestOpen_Degraded(t *testing.T) {
+	// 找一个没有监听的端口
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	port := l.Addr().(*net.TCPAddr).Port
+	l.Close()
+
+	m := Mysql{Path: "127.0.0.1", Port: port, Dbname: "test", LogMode: "silent",
+		MaxIdleConns: 1, MaxOpenConns: 1,
+		Retry: gormc.RetryConf{MaxAttempts: 2, InitialInterval: 10 * time.Millisecond}}
+	if _, err := Open(m); err == nil {
+		t.Fatal("Expected an error without degraded mode")
+	}
+
+	// 降级启动时不访问数据库，第一次查询才连接
+	m.Retry.Degraded = true
+	db, err := Open(m)
+	if err != nil {
+		t.Fatalf("Expected to start degraded, got %v", err)
+	}
+	sqlDB, _ := db.DB()
+	defer sqlDB.Close()
+	if err := sqlDB.Ping(); err == nil {
+		t.Error("Expected the database to be unreachable")
+	}
+}
+
+func TestMysql_Dsn(t *testing.T) {
+	m := Mysql{Path: "10.0.0.1", Port: 3306, Dbname: "orders", Username: "app", Password: "p@ss/w:rd?",
+		Charset: "utf8mb4", Collation: "utf8mb4_unicode_ci", ParseTime: true, Loc: "Asia/Shanghai",
+		Timeout: time.Second, ReadTimeout: 3 * time.Second, WriteTimeout: 5 * time.Second,
+		InterpolateParams: true, TlsMode: "false"}
+
+	// 用驱动解析生成的 DSN，确认参数和转义正确
+	cfg, err := gomysql.ParseDSN(m.Dsn())
+	if err != nil {
+		t.Fatalf("Failed to parse %q: %v", m.Dsn(), err)
+	}
+	if cfg.Passwd != m.Password || cfg.Addr != "10.0.0.1:3306" || cfg.DBName != "orders" {
+		t.Errorf("Unexpected credentials or address: %+v", cfg)
+	}
+	if cfg.Collation != m.Collation || !cfg.ParseTime || !cfg.InterpolateParams {
+		t.Errorf("Unexpected options: %+v", cfg)
+	}
+	if cfg.Loc.String() != "Asia/Shanghai" {
+		t.Errorf("Expected Asia/Shanghai, got %s", cfg.Loc)
+	}
+	if cfg.Timeout != time.Second || cfg.ReadTimeout != 3*time.Second || cfg.WriteTimeout != 5*time.Second {
+		t.Errorf("Unexpected timeouts: %+v", cfg)
+	}
+	if len(cfg.TLSConfig) > 0 {
+		t.Errorf("Expected no TLS, got %s", cfg.TLSConfig)
+	}
+
+	m.Loc = "Nowhere/City"
+	if _, err := m.dsn(m.Path, m.Port); err == nil {
+		t.Error("Expected an error for an invalid location")
+	}
+}
+
+func TestMysql_DsnOverride(t *testing.T) {
+	// Config 覆盖结构化配置，兼容旧的整体 URL 编码写法
+	for _, override := range []string{"parseTime=false&loc=UTC", "parseTime%3Dfalse%26loc%3DUTC"} {
+		m := Mysql{Path: "localhost", Port: 3306, Dbname: "orders", ParseTime: true, Loc: "Local", Config: override}
+		cfg, err := gomysql.ParseDSN(m.Dsn())
+		if err != nil {
+			t.Fatalf("Failed to parse %q: %v", m.Dsn(), err)
+		}
+		if cfg.ParseTime || cfg.Loc != time.UTC {
+			t.Errorf("Expected %s to override, got %q", override, m.Dsn())
+		}
+	}
+}
+
+func TestMysql_DsnTLS(t *testing.T) {
+	ca := writeTestCA(t)
+
+	m := Mysql{Path: "10.0.0.1", Port: 3306, Dbname: "orders", TlsMode: "false", TlsCA: ca}
+	cfg, err := gomysql.ParseDSN(m.Dsn())
+	if err != nil {
+		t.Fatalf("Failed to parse %q: %v", m.Dsn(), err)
+	}
+	// 配置了证书时注册自定义 TLS 配置
+	if !strings.HasPrefix(cfg.TLSConfig, "gormc-10.0.0.1-3306-") || cfg.TLS == nil || cfg.TLS.RootCAs == nil {
+		t.Fatalf("Expected the registered TLS config, got %q", cfg.TLSConfig)
+	}
+	if cfg.TLS.ServerName != "10.0.0.1" {
+		t.Errorf("Expected server name 10.0.0.1, got %s", cfg.TLS.ServerName)
+	}
+
+	// 同一地址换了证书注册为另一个名字，不覆盖前一个配置
+	other := Mysql{Path: "10.0.0.1", Port: 3306, Dbname: "orders", TlsMode: "custom", TlsCA: writeTestCA(t)}
+	otherCfg, err := gomysql.ParseDSN(other.Dsn())
+	if err != nil {
+		t.Fatalf("Failed to parse %q: %v", other.Dsn(), err)
+	}
+	if otherCfg.TLSConfig == cfg.TLSConfig {
+		t.Errorf("Expected another TLS config name for other certificates, got %q", otherCfg.TLSConfig)
+	}
+	if again, err := gomysql.ParseDSN(m.Dsn()); err != nil || again.TLSConfig != cfg.TLSConfig ||
+		!again.TLS.RootCAs.Equal(cfg.TLS.RootCAs) {
+		t.Errorf("Expected the same name and CA for the same certificates, got %q (%v)", again.TLSConfig, err)
+	}
+
+	m = Mysql{Path: "10.0.0.1", Port: 3306, Dbname: "orders", TlsMode: "skip-verify"}
+	if cfg, err = gomysql.ParseDSN(m.Dsn()); err != nil || cfg.TLSConfig != "skip-verify" {
+		t.Errorf("Expected skip-verify, got %q, %v", cfg.TLSConfig, err)
+	}
+
+	// 证书错误通过 DsnE 返回，Dsn 记录日志并返回空
+	m = Mysql{Path: "10.0.0.1", Port: 3306, Dbname: "orders", TlsMode: "custom", TlsCA: filepath.Join(t.TempDir(), "none.pem")}
+	if _, err := m.DsnE(); err == nil {
+		t.Error("Expected an error for a missing CA")
+	}
+	if dsn := m.Dsn(); dsn != "" {
+		t.Errorf("Expected an empty DSN for a missing CA, got %q", dsn)
+	}
+}
+
+// writeTestCA 生成一个自签名 CA 证书写入临时文件
+func writeTestCA(t *testing.T) string {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test ca"},
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Failed to create certificate: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatalf("Failed to write certificate: %v", err)
+	}
+	return path
+}
+
+func TestOpen_Credentials(t *testing.T) {
+	// 找一个没有监听的端口
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	port := l.Addr().(*net.TCPAddr).Port
+	l.Close()
+
+	m := Mysql{Path: "127.0.0.1", Port: port, Dbname: "test", LogMode: "silent",
+		MaxIdleConns: 1, MaxOpenConns: 1, Retry: gormc.RetryConf{Degraded: true},
+		Credentials: gormc.CredentialConf{Provider: "missing"}}
+	if _, err := Open(m); err == nil {
+		t.Fatal("Expected an error for an unregistered provider")
+	}
+
+	// 每次新建连接都向提供者取凭据
+	var calls atomic.Int32
+	provider := gormc.CredentialProviderFunc(func(context.Context) (gormc.Credentials, error) {
+		calls.Add(1)
+		return gormc.Credentials{Password: "rotated"}, nil
+	})
+	db, err := Open(m, config.WithCredentials(provider))
+	if err != nil {
+		t.Fatalf("Expected to start degraded, got %v", err)
+	}
+	sqlDB, _ := db.DB()
+	defer sqlDB.Close()
+	before := calls.Load()
+	if err := sqlDB.Ping(); err == nil {
+		t.Error("Expected the database to be unreachable")
+	}
+	if calls.Load() == before {
+		t.Error("Expected the provider to be called for the new connection")
+	}
+}
@@ -0,0 +1,158 @@
+package config
+
+import (
+	"time"
+
+	"github.com/huof6829/gorm-zero/gormc"
+	"github.com/huof6829/gorm-zero/gormc/plugins"
+	"gorm.io/gorm"
+	gormLogger "gorm.io/gorm/logger"
+)
+
+const (
+	// LoggerGorm logs with the default gorm logger, into stderr.
+	LoggerGorm = "gorm"
+	// LoggerZero logs with go-zero logx.
+	LoggerZero = "zero"
+)
+
+type (
+	// Option customizes how a database is opened.
+	Option func(o *Options)
+
+	// Options are the settings shared by the drivers to open a database,
+	// so that logging, plugins and the pool don't depend on the function called.
+	Options struct {
+		GormConfig   *gorm.Config
+		Logger       string // gorm or zero, ignored if GormConfig has a logger
+		Plugins      []gorm.Plugin
+		NoTracing    bool
+		MaxIdleConns int                      // overrides the config if positive
+		MaxOpenConns int                      // overrides the config if positive
+		Credentials  gormc.CredentialProvider // overrides the Credentials of the config if set
+	}
+
+	// Pool is the connection pool settings of a config, zero durations mean no limit.
+	Pool struct {
+		MaxIdleConns    int
+		MaxOpenConns    int
+		ConnMaxLifetime time.Duration
+		ConnMaxIdleTime time.Duration
+	}
+)
+
+// WithGormConfig opens the database with cfg, its logger is kept if set.
+func WithGormConfig(cfg *gorm.Config) Option {
+	return func(o *Options) {
+		o.GormConfig = cfg
+	}
+}
+
+// WithZeroLogger logs with go-zero logx instead of the default gorm logger.
+func WithZeroLogger() Option {
+	return func(o *Options) {
+		o.Logger = LoggerZero
+	}
+}
+
+// WithPlugins registers plugins after the tracing plugin.
+func WithPlugins(plugins ...gorm.Plugin) Option {
+	return func(o *Options) {
+		o.Plugins = append(o.Plugins, plugins...)
+	}
+}
+
+// WithoutTracing doesn't register the tracing plugin.
+func WithoutTracing() Option {
+	return func(o *Options) {
+		o.NoTracing = true
+	}
+}
+
+// WithPool overrides the pool sizes of the config.
+func WithPool(maxIdleConns, maxOpenConns int) Option {
+	return func(o *Options) {
+		o.MaxIdleConns = maxIdleConns
+		o.MaxOpenConns = maxOpenConns
+	}
+}
+
+// WithCredentials reads the credentials of each new connection from p, e.g. a secret store,
+// instead of the Username and Password of the config.
+func WithCredentials(p gormc.CredentialProvider) Option {
+	return func(o *Options) {
+		o.Credentials = p
+	}
+}
+
+// NewOptions returns the Options built from opts.
+func NewOptions(opts ...Option) *Options {
+	o := &Options{Logger: LoggerGorm}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// Config returns the gorm.Config to open the database, with the logger built from cfg if not set.
+func (o *Options) Config(cfg GormLogConfigI) *gorm.Config {
+	var gormCfg gorm.Config
+	if o.GormConfig != nil {
+		gormCfg = *o.GormConfig
+	}
+	if gormCfg.Logger == nil {
+		gormCfg.Logger = o.newLogger(cfg)
+	}
+	return &gormCfg
+}
+
+// CredentialProvider returns the CredentialProvider of o, or the one configured by conf, nil if none.
+func (o *Options) CredentialProvider(conf gormc.CredentialConf) (gormc.CredentialProvider, error) {
+	if o.Credentials != nil {
+		return o.Credentials, nil
+	}
+	return conf.NewProvider()
+}
+
+// Use registers the tracing plugin and the plugins of o into db.
+func (o *Options) Use(db *gorm.DB) error {
+	if !o.NoTracing {
+		if err := plugins.InitPlugins(db); err != nil {
+			return err
+		}
+	}
+	for _, plugin := range o.Plugins {
+		if err := db.Use(plugin); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetPool sets the pool of db, the sizes of o take precedence over the ones of pool.
+func (o *Options) SetPool(db *gorm.DB, pool Pool) error {
+	if o.MaxIdleConns > 0 {
+		pool.MaxIdleConns = o.MaxIdleConns
+	}
+	if o.MaxOpenConns > 0 {
+		pool.MaxOpenConns = o.MaxOpenConns
+	}
+
+	sqldb, err := db.DB()
+	if err != nil {
+		return err
+	}
+	sqldb.SetMaxIdleConns(pool.MaxIdleConns)
+	sqldb.SetMaxOpenConns(pool.MaxOpenConns)
+	// recycle the connections before the proxies in between drop them
+	sqldb.SetConnMaxLifetime(pool.ConnMaxLifetime)
+	sqldb.SetConnMaxIdleTime(pool.ConnMaxIdleTime)
+	return nil
+}
+
+func (o *Options) newLogger(cfg GormLogConfigI) gormLogger.Interface {
+	if o.Logger == LoggerZero {
+		return NewDefaultZeroLogger(cfg)
+	}
+	return NewDefaultGormLogger(cfg)
+}
@@ -1,12 +1,18 @@
 package pg
 
 import (
+	"context"
+	"database/sql"
 	"errors"
-	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/huof6829/gorm-zero/gormc"
 	"github.com/huof6829/gorm-zero/gormc/config"
 	"github.com/huof6829/gorm-zero/gormc/plugins"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
@@ -17,7 +23,6 @@ type PgSql struct {
 	Password      string
 	Path          string
 	Port          int    `json:",default=5432"`
-	SslMode       string `json:",default=disable,options=disable|enable"`
 	TimeZone      string `json:",default=Asia/Shanghai"`
 	Dbname        string
 	MaxIdleConns  int    `json:",default=10"`                               // 空闲中的最大连接数
@@ -25,11 +30,85 @@ type PgSql struct {
 	LogMode       string `json:",default=dev,options=dev|test|prod|silent"` // 是否开启Gorm全局日志
 	LogColorful   bool   `json:",default=false"`                            // 是否开启日志高亮
 	SlowThreshold int64  `json:",default=1000"`
+
+	// SSL 模式，enable 同 require，为兼容旧配置保留
+	SslMode     string `json:",default=disable,options=disable|allow|prefer|require|verify-ca|verify-full|enable"`
+	SslRootCert string `json:",optional"` // CA 证书路径，verify-ca 和 verify-full 需要
+	SslCert     string `json:",optional"` // 客户端证书路径
+	SslKey      string `json:",optional"` // 客户端私钥路径
+
+	ConnMaxLifetime time.Duration `json:",optional"` // 连接最大存活时间，0 为不限制，应小于中间代理的超时
+	ConnMaxIdleTime time.Duration `json:",optional"` // 连接最大空闲时间，0 为不限制
+
+	SearchPath       string        `json:",optional"` // schema 搜索路径，如 app,public
+	StatementTimeout time.Duration `json:",optional"` // 语句超时，0 为不限制
+	LockTimeout      time.Duration `json:",optional"` // 等锁超时，0 为不限制
+	ApplicationName  string        `json:",optional"` // 连接的应用名，显示在 pg_stat_activity 中
+	// PreparedStatements uses the extended protocol with the prepared statements cached per connection,
+	// leave it off behind poolers in transaction mode such as PgBouncer.
+	PreparedStatements bool `json:",optional"`
+
+	Replicas             []string      `json:",optional"`                                  // 只读副本地址 host:port，与主库共用账号和库名
+	ReplicaPolicy        string        `json:",default=random,options=random|round-robin"` // 副本负载均衡策略
+	ReplicaCheckInterval time.Duration `json:",default=10s"`                               // 副本健康检查间隔
+
+	Retry gormc.RetryConf `json:",optional"` // 启动时连接重试
+
+	// Credentials reads the username and password of each new connection instead of Username and Password,
+	// so that rotated secrets are picked up without restart, set ConnMaxLifetime to drain the old connections.
+	Credentials gormc.CredentialConf `json:",optional"`
 }
 
 func (m *PgSql) Dsn() string {
-	return fmt.Sprintf("user=%s password=%s dbname=%s host=%s port=%d sslmode=%s TimeZone=%s", m.Username, m.Password, m.Dbname, m.Path, m.Port, m.SslMode, m.TimeZone)
+	return m.dsn(m.Path, m.Port)
+}
+
+func (m *PgSql) dsn(path string, port int) string {
+	sslMode := m.SslMode
+	if sslMode == "enable" {
+		sslMode = "require"
+	}
+	dsn := []string{
+		"user=" + quoteDsnValue(m.Username),
+		"password=" + quoteDsnValue(m.Password),
+		"dbname=" + quoteDsnValue(m.Dbname),
+		"host=" + quoteDsnValue(path),
+		"port=" + strconv.Itoa(port),
+		"sslmode=" + sslMode,
+		"TimeZone=" + quoteDsnValue(m.TimeZone),
+	}
+	optional := []struct{ key, value string }{
+		{"sslrootcert", m.SslRootCert},
+		{"sslcert", m.SslCert},
+		{"sslkey", m.SslKey},
+		{"search_path", m.SearchPath},
+		{"statement_timeout", milliseconds(m.StatementTimeout)},
+		{"lock_timeout", milliseconds(m.LockTimeout)},
+		{"application_name", m.ApplicationName},
+	}
+	for _, param := range optional {
+		if len(param.value) > 0 {
+			dsn = append(dsn, param.key+"="+quoteDsnValue(param.value))
+		}
+	}
+	return strings.Join(dsn, " ")
 }
+
+// quoteDsnValue quotes value in the keyword/value connection string if it is empty or has spaces or quotes.
+func quoteDsnValue(value string) string {
+	if len(value) > 0 && !strings.ContainsAny(value, ` '\`) {
+		return value
+	}
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(value) + "'"
+}
+
+func milliseconds(d time.Duration) string {
+	if d <= 0 {
+		return ""
+	}
+	return strconv.FormatInt(d.Milliseconds(), 10)
+}
+
 func (m *PgSql) GetGormLogMode() logger.LogLevel {
 	return config.OverwriteGormLogMode(m.LogMode)
 }
@@ -41,48 +120,118 @@ func (m *PgSql) GetColorful() bool {
 	return m.LogColorful
 }
 
-func Connect(m PgSql) (*gorm.DB, error) {
+// Open opens the database of m, with the tracing plugin, the logger built from m and the pool of m by default.
+func Open(m PgSql, opts ...config.Option) (*gorm.DB, error) {
 	if m.Dbname == "" {
 		return nil, errors.New("database name is empty")
 	}
-	newLogger := config.NewDefaultGormLogger(&m)
-	pgsqlCfg := postgres.Config{
-		DSN:                  m.Dsn(),
-		PreferSimpleProtocol: true, // disables implicit prepared statement usage
+	o := config.NewOptions(opts...)
+	credentials, err := o.CredentialProvider(m.Credentials)
+	if err != nil {
+		return nil, err
 	}
-	db, err := gorm.Open(postgres.New(pgsqlCfg), &gorm.Config{
-		Logger: newLogger,
+	db, err := config.OpenWithRetry("postgres "+m.Path, m.Retry, func(degraded bool) (*gorm.DB, error) {
+		cfg := o.Config(&m)
+		cfg.DisableAutomaticPing = cfg.DisableAutomaticPing || degraded
+		dialector, err := m.dialector(m.Path, m.Port, credentials)
+		if err != nil {
+			return nil, err
+		}
+		db, err := gorm.Open(dialector, cfg)
+		if err != nil && dialector.Conn != nil {
+			// the connector pool is opened by us, gorm doesn't close it on failure
+			_ = dialector.Conn.(*sql.DB).Close()
+		}
+		return db, err
 	})
 	if err != nil {
 		return nil, err
-	} else {
-		sqldb, _ := db.DB()
-		sqldb.SetMaxIdleConns(m.MaxIdleConns)
-		sqldb.SetMaxOpenConns(m.MaxOpenConns)
-		return db, nil
 	}
+
+	if err = o.Use(db); err != nil {
+		return nil, err
+	}
+	if err = useReplicas(db, m, credentials); err != nil {
+		return nil, err
+	}
+	if err = o.SetPool(db, m.pool()); err != nil {
+		return nil, err
+	}
+	return db, nil
 }
 
+// Connect opens the database of m, it is Open without options.
+func Connect(m PgSql) (*gorm.DB, error) {
+	return Open(m)
+}
+
+// ConnectWithConfig opens the database of m with cfg, it is Open with config.WithGormConfig.
 func ConnectWithConfig(m PgSql, cfg *gorm.Config) (*gorm.DB, error) {
-	if m.Dbname == "" {
-		return nil, errors.New("database name is empty")
+	return Open(m, config.WithGormConfig(cfg))
+}
+
+func useReplicas(db *gorm.DB, m PgSql, credentials gormc.CredentialProvider) error {
+	if len(m.Replicas) == 0 {
+		return nil
 	}
-	pgsqlCfg := postgres.Config{
-		DSN:                  m.Dsn(),
-		PreferSimpleProtocol: true, // disables implicit prepared statement usage
+
+	replicas := make([]gorm.Dialector, 0, len(m.Replicas))
+	for _, addr := range m.Replicas {
+		host, port, err := config.SplitReplicaAddr(addr, m.Port)
+		if err != nil {
+			return err
+		}
+		dialector, err := m.dialector(host, port, credentials)
+		if err != nil {
+			return err
+		}
+		replicas = append(replicas, dialector)
 	}
-	db, err := gorm.Open(postgres.New(pgsqlCfg), cfg)
-	if err != nil {
-		return nil, err
+	return db.Use(plugins.NewReplicaPlugin(replicas, plugins.ReplicaConf{
+		Policy:          m.ReplicaPolicy,
+		CheckInterval:   m.ReplicaCheckInterval,
+		MaxIdleConns:    m.MaxIdleConns,
+		MaxOpenConns:    m.MaxOpenConns,
+		ConnMaxLifetime: m.ConnMaxLifetime,
+		ConnMaxIdleTime: m.ConnMaxIdleTime,
+	}))
+}
+
+// dialector returns the dialector of the server at path:port.
+// With credentials, the connections are opened by a connector taking the credentials of each new connection.
+func (m *PgSql) dialector(path string, port int, credentials gormc.CredentialProvider) (*postgres.Dialector, error) {
+	pgsqlCfg := &postgres.Config{
+		DSN:                  m.dsn(path, port),
+		PreferSimpleProtocol: !m.PreparedStatements, // disables implicit prepared statement usage by default
+	}
+	if credentials == nil {
+		return &postgres.Dialector{Config: pgsqlCfg}, nil
 	}
 
-	err = plugins.InitPlugins(db)
+	connConfig, err := pgx.ParseConfig(pgsqlCfg.DSN)
 	if err != nil {
 		return nil, err
 	}
-	sqldb, _ := db.DB()
-	sqldb.SetMaxIdleConns(m.MaxIdleConns)
-	sqldb.SetMaxOpenConns(m.MaxOpenConns)
-	return db, nil
+	if pgsqlCfg.PreferSimpleProtocol {
+		connConfig.DefaultQueryExecMode = pgx.QueryExecModeSimpleProtocol
+	}
+	pgsqlCfg.Conn = stdlib.OpenDB(*connConfig, stdlib.OptionBeforeConnect(
+		func(ctx context.Context, cfg *pgx.ConnConfig) error {
+			creds, err := gormc.ResolveCredentials(ctx, credentials, m.Username)
+			if err != nil {
+				return err
+			}
+			cfg.User, cfg.Password = creds.Username, creds.Password
+			return nil
+		}))
+	return &postgres.Dialector{Config: pgsqlCfg}, nil
+}
 
+func (m *PgSql) pool() config.Pool {
+	return config.Pool{
+		MaxIdleConns:    m.MaxIdleConns,
+		MaxOpenConns:    m.MaxOpenConns,
+		ConnMaxLifetime: m.ConnMaxLifetime,
+		ConnMaxIdleTime: m.ConnMaxIdleTime,
+	}
 }
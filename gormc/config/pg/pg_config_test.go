@@ -0,0 +1,80 @@
+package pg
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+func TestPgSql_Dsn(t *testing.T) {
+	m := PgSql{
+		Username:         "app",
+		Password:         "p@ss 'word'",
+		Path:             "10.0.0.1",
+		Port:             5432,
+		Dbname:           "orders",
+		SslMode:          "verify-full",
+		TimeZone:         "Asia/Shanghai",
+		SearchPath:       "app, public",
+		StatementTimeout: 5 * time.Second,
+		LockTimeout:      time.Second,
+		ApplicationName:  "orders-api",
+	}
+
+	// 用 pgx 解析生成的 DSN，确认参数和转义正确
+	cfg, err := pgx.ParseConfig(m.Dsn())
+	if err != nil {
+		t.Fatalf("Failed to parse %q: %v", m.Dsn(), err)
+	}
+	if cfg.Password != m.Password {
+		t.Errorf("Expected password %q, got %q", m.Password, cfg.Password)
+	}
+	if cfg.TLSConfig == nil || cfg.TLSConfig.ServerName != "10.0.0.1" {
+		t.Error("Expected verify-full TLS")
+	}
+	expected := map[string]string{
+		"TimeZone":          "Asia/Shanghai",
+		"search_path":       "app, public",
+		"statement_timeout": "5000",
+		"lock_timeout":      "1000",
+		"application_name":  "orders-api",
+	}
+	for key, value := range expected {
+		if got := cfg.RuntimeParams[key]; got != value {
+			t.Errorf("Expected %s=%q, got %q", key, value, got)
+		}
+	}
+}
+
+func TestPgSql_DsnDefaults(t *testing.T) {
+	m := PgSql{Username: "app", Path: "localhost", Port: 5432, Dbname: "orders", SslMode: "enable"}
+
+	cfg, err := pgx.ParseConfig(m.Dsn())
+	if err != nil {
+		t.Fatalf("Failed to parse %q: %v", m.Dsn(), err)
+	}
+	// enable 兼容为 require，要求 TLS 但不校验证书
+	if cfg.TLSConfig == nil || !cfg.TLSConfig.InsecureSkipVerify {
+		t.Error("Expected require TLS")
+	}
+	for _, key := range []string{"search_path", "statement_timeout", "lock_timeout", "application_name"} {
+		if _, ok := cfg.RuntimeParams[key]; ok {
+			t.Errorf("Expected no %s", key)
+		}
+	}
+}
+
+func TestPgSql_DsnCerts(t *testing.T) {
+	m := PgSql{Path: "localhost", Port: 5432, Dbname: "orders", SslMode: "verify-ca",
+		SslRootCert: "/etc/ssl/ca.pem", SslCert: "/etc/ssl/client.pem", SslKey: "/etc/ssl/client key.pem"}
+
+	dsn := m.Dsn()
+	for _, param := range []string{"sslmode=verify-ca", "sslrootcert=/etc/ssl/ca.pem",
+		"sslcert=/etc/ssl/client.pem", "sslkey='/etc/ssl/client key.pem'"} {
+		if !strings.Contains(dsn, param) {
+			t.Errorf("Expected %s in %q", param, dsn)
+		}
+	}
+}
@@ -0,0 +1,26 @@
+package config
+
+import (
+	"context"
+
+	"github.com/huof6829/gorm-zero/gormc"
+	"github.com/zeromicro/go-zero/core/logx"
+	"gorm.io/gorm"
+)
+
+// OpenWithRetry calls open until it succeeds, retrying as retry says.
+// If it still fails and retry.Degraded is set, open is called once more with degraded,
+// which must open the database without touching it, so that it connects on the first query.
+func OpenWithRetry(name string, retry gormc.RetryConf, open func(degraded bool) (*gorm.DB, error)) (*gorm.DB, error) {
+	var db *gorm.DB
+	err := gormc.RetryCtx(context.Background(), name, retry, func(context.Context) (err error) {
+		db, err = open(false)
+		return err
+	})
+	if err == nil || !retry.Degraded {
+		return db, err
+	}
+
+	logx.Errorf("gormc: starting with %s unreachable, connecting on the first query: %v", name, err)
+	return open(true)
+}
@@ -0,0 +1,105 @@
+package sqlite
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/huof6829/gorm-zero/gormc/config"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+const memoryPath = ":memory:"
+
+type Sqlite struct {
+	Path          string `json:",default=:memory:"`                         // 数据库文件路径，:memory: 为内存数据库
+	Config        string `json:",optional"`                                 // 其他 DSN 参数，如 cache=shared
+	WAL           bool   `json:",default=true"`                             // 是否开启 WAL 日志模式，内存数据库忽略
+	BusyTimeout   int64  `json:",default=5000"`                             // 锁等待超时，毫秒
+	ForeignKeys   bool   `json:",default=true"`                             // 是否开启外键约束
+	MaxIdleConns  int    `json:",default=10"`                               // 空闲中的最大连接数
+	MaxOpenConns  int    `json:",default=10"`                               // 打开到数据库的最大连接数
+	LogMode       string `json:",default=dev,options=dev|test|prod|silent"` // 是否开启Gorm全局日志
+	LogColorful   bool   `json:",default=false"`                            // 是否开启日志高亮
+	SlowThreshold int64  `json:",default=1000"`
+}
+
+func (m *Sqlite) IsMemory() bool {
+	return m.Path == "" || m.Path == memoryPath
+}
+
+func (m *Sqlite) Dsn() string {
+	params := url.Values{}
+	if m.BusyTimeout > 0 {
+		params.Set("_busy_timeout", fmt.Sprintf("%d", m.BusyTimeout))
+	}
+	if m.WAL && !m.IsMemory() {
+		params.Set("_journal_mode", "WAL")
+	}
+	if m.ForeignKeys {
+		params.Set("_foreign_keys", "1")
+	}
+
+	path := m.Path
+	if m.IsMemory() {
+		path = memoryPath
+	}
+	query := params.Encode()
+	if extra := strings.TrimPrefix(m.Config, "?"); len(extra) > 0 {
+		if len(query) > 0 {
+			query += "&"
+		}
+		query += extra
+	}
+	if len(query) == 0 {
+		return "file:" + path
+	}
+	return "file:" + path + "?" + query
+}
+
+func (m *Sqlite) GetGormLogMode() logger.LogLevel {
+	return config.OverwriteGormLogMode(m.LogMode)
+}
+
+func (m *Sqlite) GetSlowThreshold() time.Duration {
+	return time.Duration(m.SlowThreshold) * time.Millisecond
+}
+func (m *Sqlite) GetColorful() bool {
+	return m.LogColorful
+}
+
+// Open opens the database of m, with the tracing plugin, the logger built from m and the pool of m by default.
+func Open(m Sqlite, opts ...config.Option) (*gorm.DB, error) {
+	o := config.NewOptions(opts...)
+	db, err := gorm.Open(sqlite.Open(m.Dsn()), o.Config(&m))
+	if err != nil {
+		return nil, err
+	}
+
+	if err = o.Use(db); err != nil {
+		return nil, err
+	}
+	if m.IsMemory() {
+		// 每个连接都是独立的内存数据库，只保留一个连接，且不能被回收
+		err = config.NewOptions().SetPool(db, config.Pool{MaxIdleConns: 1, MaxOpenConns: 1})
+	} else {
+		err = o.SetPool(db, config.Pool{MaxIdleConns: m.MaxIdleConns, MaxOpenConns: m.MaxOpenConns})
+	}
+	if err != nil {
+		return nil, err
+	}
+	return db, nil
+}
+
+// Connect opens the database of m, it is Open without options.
+func Connect(m Sqlite) (*gorm.DB, error) {
+	return Open(m)
+}
+
+// ConnectWithConfig opens the database of m with cfg, it is Open with config.WithGormConfig.
+func ConnectWithConfig(m Sqlite, cfg *gorm.Config) (*gorm.DB, error) {
+	return Open(m, config.WithGormConfig(cfg))
+}
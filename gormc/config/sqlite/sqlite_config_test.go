@@ -0,0 +1,87 @@
+package sqlite
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/huof6829/gorm-zero/gormc/config"
+	"github.com/zeromicro/go-zero/core/conf"
+	"gorm.io/gorm"
+)
+
+func TestSqlite_Dsn(t *testing.T) {
+	var m Sqlite
+	if err := conf.LoadFromYamlBytes([]byte("Path: /data/app.db\nConfig: cache=shared"), &m); err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	want := "file:/data/app.db?_busy_timeout=5000&_foreign_keys=1&_journal_mode=WAL&cache=shared"
+	if dsn := m.Dsn(); dsn != want {
+		t.Errorf("Expected %s, got %s", want, dsn)
+	}
+
+	// 内存数据库不开启 WAL
+	memory := Sqlite{WAL: true}
+	if dsn := memory.Dsn(); dsn != "file::memory:" {
+		t.Errorf("unexpected memory dsn: %s", dsn)
+	}
+}
+
+func TestConnect(t *testing.T) {
+	for _, path := range []string{":memory:", filepath.Join(t.TempDir(), "test.db")} {
+		db, err := Connect(Sqlite{Path: path, WAL: true, BusyTimeout: 1000, ForeignKeys: true,
+			MaxIdleConns: 2, MaxOpenConns: 2, LogMode: "silent"})
+		if err != nil {
+			t.Fatalf("Connect %s failed: %v", path, err)
+		}
+
+		var fk int
+		if err := db.Raw("PRAGMA foreign_keys").Scan(&fk).Error; err != nil || fk != 1 {
+			t.Errorf("Expected foreign keys on for %s, got %d (%v)", path, fk, err)
+		}
+		sqlDB, _ := db.DB()
+		sqlDB.Close()
+	}
+}
+
+func TestOpen_Options(t *testing.T) {
+	m := Sqlite{Path: filepath.Join(t.TempDir(), "test.db"), MaxIdleConns: 2, MaxOpenConns: 2, LogMode: "silent"}
+
+	// Connect 和 ConnectWithConfig 都注册 tracing 插件并设置连接池
+	for _, connect := range []func() (*gorm.DB, error){
+		func() (*gorm.DB, error) { return Connect(m) },
+		func() (*gorm.DB, error) { return ConnectWithConfig(m, &gorm.Config{}) },
+	} {
+		db, err := connect()
+		if err != nil {
+			t.Fatalf("Connect failed: %v", err)
+		}
+		if _, ok := db.Config.Plugins["gorm-zero-tracing-plugin"]; !ok {
+			t.Error("Expected the tracing plugin")
+		}
+		if db.Logger == nil {
+			t.Error("Expected a logger")
+		}
+		sqlDB, _ := db.DB()
+		if n := sqlDB.Stats().MaxOpenConnections; n != 2 {
+			t.Errorf("Expected 2 open connections at most, got %d", n)
+		}
+		sqlDB.Close()
+	}
+
+	db, err := Open(m, config.WithoutTracing(), config.WithZeroLogger(), config.WithPool(1, 5))
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer func() {
+		sqlDB, _ := db.DB()
+		sqlDB.Close()
+	}()
+	if _, ok := db.Config.Plugins["gorm-zero-tracing-plugin"]; ok {
+		t.Error("Expected no tracing plugin")
+	}
+	sqlDB, _ := db.DB()
+	if n := sqlDB.Stats().MaxOpenConnections; n != 5 {
+		t.Errorf("Expected 5 open connections at most, got %d", n)
+	}
+}
@@ -0,0 +1,329 @@
+package gormc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/zeromicro/go-zero/core/logx"
+	"github.com/zeromicro/go-zero/core/stringx"
+	"github.com/zeromicro/go-zero/core/threading"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// language=lua
+	counterIncrScript = redis.NewScript(`
+redis.call("HINCRBY", KEYS[1], ARGV[2], ARGV[1])
+if redis.call("EXISTS", KEYS[2]) == 1 then
+	return redis.call("INCRBY", KEYS[2], ARGV[1])
+end
+return false`)
+
+	// language=lua
+	counterSwapScript = redis.NewScript(`
+if redis.call("EXISTS", KEYS[2]) == 0 and redis.call("EXISTS", KEYS[1]) == 1 then
+	redis.call("RENAME", KEYS[1], KEYS[2])
+end
+return redis.call("EXISTS", KEYS[2], KEYS[3])`)
+
+	// counterTakeScript moves up to ARGV[1] deltas from the flushing hash into the batch hash under the id ARGV[2],
+	// unless a batch left by a failed flush exists, and returns the id and the deltas of the batch.
+	// language=lua
+	counterTakeScript = redis.NewScript(`
+if redis.call("EXISTS", KEYS[2]) == 0 then
+	local deltas = redis.call("HSCAN", KEYS[1], 0, "COUNT", ARGV[1])[2]
+	if #deltas == 0 then
+		return false
+	end
+	redis.call("HSET", KEYS[2], unpack(deltas))
+	for i = 1, #deltas, 2 do
+		redis.call("HDEL", KEYS[1], deltas[i])
+	end
+	redis.call("SET", KEYS[3], ARGV[2])
+end
+return {redis.call("GET", KEYS[3]), redis.call("HGETALL", KEYS[2])}`)
+
+	// language=lua
+	counterUnlockScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`)
+)
+
+type (
+	// CounterConf is the configuration of a Counter.
+	CounterConf struct {
+		Table     string // table of the counter column
+		Column    string // counter column, e.g. view_count
+		KeyColumn string `json:",default=id"` // column identifying the rows, usually the primary key
+		// Prefix is the prefix of the redis keys, e.g. "counter:post:views:"
+		Prefix        string
+		Expiry        time.Duration `json:",default=1h"`  // TTL of the totals cached in redis
+		FlushInterval time.Duration `json:",default=5s"`  // interval of flushing the deltas into the table
+		BatchSize     int           `json:",default=500"` // number of rows updated in a transaction
+		// BatchTable records the ids of the applied batches in the transactions applying them,
+		// see CounterBatch. Without it a batch interrupted after its commit is applied again.
+		BatchTable string `json:",optional"`
+	}
+
+	// CounterBatch is a row of CounterConf.BatchTable, to be migrated with
+	// db.Table(name).AutoMigrate(&CounterBatch{}). Old rows can be deleted by CreatedAt,
+	// once no interrupted flush can be retried, e.g. after a day.
+	CounterBatch struct {
+		ID        string    `gorm:"primaryKey;size:32"`
+		CreatedAt time.Time `gorm:"index"`
+	}
+
+	// Counter increments counters in redis and flushes the accumulated deltas into the table periodically.
+	//
+	// Every increment is recorded in a redis hash of pending deltas before it is acknowledged,
+	// the hash is swapped out and applied to the table in batches by Flush, so increments survive restarts
+	// as long as redis persists them. A batch is removed from redis after its transaction commits,
+	// if the process dies in between, the batch is applied again on the next flush, unless
+	// CounterConf.BatchTable is set. Increments are counted at least once: an IncrCtx failing
+	// on a timeout may have been applied, retrying it may count it twice.
+	Counter struct {
+		db          *gorm.DB
+		cache       *RedisCache
+		conf        CounterConf
+		tag         string
+		pendingKey  string
+		flushingKey string
+		batchKey    string
+		batchIDKey  string
+		lockKey     string
+		done        chan struct{}
+		stopOnce    sync.Once
+		wg          sync.WaitGroup
+	}
+)
+
+// NewCounter returns a Counter, call Start to flush periodically.
+func NewCounter(db *gorm.DB, cache *RedisCache, conf CounterConf) (*Counter, error) {
+	if len(conf.Table) == 0 || len(conf.Column) == 0 || len(conf.Prefix) == 0 {
+		return nil, errors.New("counter config error: Table, Column and Prefix are required")
+	}
+	if len(conf.KeyColumn) == 0 {
+		conf.KeyColumn = "id"
+	}
+	if conf.Expiry <= 0 {
+		conf.Expiry = time.Hour
+	}
+	if conf.FlushInterval <= 0 {
+		conf.FlushInterval = 5 * time.Second
+	}
+	if conf.BatchSize <= 0 {
+		conf.BatchSize = 500
+	}
+
+	// the hash tag keeps the totals and the bookkeeping keys in the same slot, so that they can be updated
+	// by the same script in cluster mode
+	tag := "{" + conf.Prefix + "}"
+	return &Counter{
+		db:          db,
+		cache:       cache,
+		conf:        conf,
+		tag:         tag,
+		pendingKey:  tag + "pending",
+		flushingKey: tag + "flushing",
+		batchKey:    tag + "batch",
+		batchIDKey:  tag + "batch:id",
+		lockKey:     tag + "lock",
+		done:        make(chan struct{}),
+	}, nil
+}
+
+// IncrCtx adds delta to the counter of id and returns the new total.
+// The pending delta and the cached total are updated by one script, so either both are or neither is.
+func (c *Counter) IncrCtx(ctx context.Context, id interface{}, delta int64) (int64, error) {
+	field := fmt.Sprint(id)
+	client := c.cache.primary()
+	total, err := counterIncrScript.Run(ctx, client, []string{c.pendingKey, c.totalKey(field)}, delta, field).Int64()
+	switch {
+	case err == nil:
+		return total, nil
+	case errors.Is(err, redis.Nil):
+		// not cached, the pending delta is counted when loading
+		return c.load(ctx, client, field)
+	default:
+		return 0, err
+	}
+}
+
+// GetCtx returns the total of the counter of id, ErrNotFound if the row doesn't exist.
+func (c *Counter) GetCtx(ctx context.Context, id interface{}) (int64, error) {
+	field := fmt.Sprint(id)
+	client := c.cache.primary()
+	total, err := client.Get(ctx, c.totalKey(field)).Int64()
+	switch {
+	case err == nil:
+		return total, nil
+	case errors.Is(err, redis.Nil):
+		return c.load(ctx, client, field)
+	default:
+		return 0, err
+	}
+}
+
+// load caches the total of field, which is the value in the table plus the deltas not flushed yet.
+// A flush committing concurrently may make the cached total drift until it expires.
+func (c *Counter) load(ctx context.Context, client redis.Cmdable, field string) (int64, error) {
+	var values []int64
+	err := c.db.WithContext(ctx).Table(c.conf.Table).Where(c.conf.KeyColumn+" = ?", field).
+		Limit(1).Pluck(c.conf.Column, &values).Error
+	if err != nil {
+		return 0, err
+	}
+	if len(values) == 0 {
+		return 0, ErrNotFound
+	}
+
+	total := values[0]
+	for _, key := range []string{c.pendingKey, c.flushingKey, c.batchKey} {
+		delta, err := client.HGet(ctx, key, field).Int64()
+		if err != nil && !errors.Is(err, redis.Nil) {
+			return 0, err
+		}
+		total += delta
+	}
+
+	// another caller may have loaded and incremented it meanwhile, keep the existing total
+	key := c.totalKey(field)
+	if err := client.SetNX(ctx, key, total, c.conf.Expiry).Err(); err != nil {
+		return 0, err
+	}
+	return client.Get(ctx, key).Int64()
+}
+
+// FlushCtx applies the accumulated deltas to the table, only one instance flushes at a time.
+func (c *Counter) FlushCtx(ctx context.Context) error {
+	// the whole flush runs on the primary of its start, a switch over meanwhile is picked up by the next one
+	client := c.cache.primary()
+	token := stringx.Randn(16)
+	ok, err := client.SetNX(ctx, c.lockKey, token, c.conf.FlushInterval*10).Result()
+	if err != nil || !ok {
+		return err
+	}
+	defer func() {
+		if err := counterUnlockScript.Run(context.WithoutCancel(ctx), client, []string{c.lockKey}, token).Err(); err != nil {
+			logx.WithContext(ctx).Errorf("gormc: failed to release counter lock %s: %v", c.lockKey, err)
+		}
+	}()
+
+	// a flushing hash or a batch left by a failed flush is applied before swapping in the new deltas
+	exists, err := counterSwapScript.Run(ctx, client, []string{c.pendingKey, c.flushingKey, c.batchKey}).Int()
+	if err != nil || exists == 0 {
+		return err
+	}
+
+	for {
+		res, err := counterTakeScript.Run(ctx, client, []string{c.flushingKey, c.batchKey, c.batchIDKey},
+			c.conf.BatchSize, stringx.Randn(16)).Slice()
+		if errors.Is(err, redis.Nil) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		id, _ := res[0].(string)
+		values, _ := res[1].([]interface{})
+		deltas := make([]string, 0, len(values))
+		for _, value := range values {
+			deltas = append(deltas, fmt.Sprint(value))
+		}
+		if err := c.flushBatch(ctx, client, id, deltas); err != nil {
+			return err
+		}
+	}
+}
+
+// flushBatch applies deltas, a flat list of field and delta pairs, in a transaction and removes the batch from redis.
+// With a BatchTable, id is recorded in the same transaction, a batch whose id is already recorded is only removed.
+func (c *Counter) flushBatch(ctx context.Context, client redis.Cmdable, id string, deltas []string) error {
+	fields := make([]string, 0, len(deltas)/2)
+	duplicate := false
+	err := c.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if len(c.conf.BatchTable) > 0 {
+			res := tx.Table(c.conf.BatchTable).Clauses(clause.OnConflict{DoNothing: true}).
+				Create(&CounterBatch{ID: id})
+			if res.Error != nil {
+				return res.Error
+			}
+			if duplicate = res.RowsAffected == 0; duplicate {
+				return nil
+			}
+		}
+
+		for i := 0; i+1 < len(deltas); i += 2 {
+			field := deltas[i]
+			fields = append(fields, field)
+			delta, err := strconv.ParseInt(deltas[i+1], 10, 64)
+			if err != nil || delta == 0 {
+				continue
+			}
+
+			err = tx.Table(c.conf.Table).Where(c.conf.KeyColumn+" = ?", field).
+				UpdateColumn(c.conf.Column, gorm.Expr(c.conf.Column+" + ?", delta)).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		metricCounterFlush.Add(float64(len(fields)), "error")
+		return err
+	}
+
+	if duplicate {
+		// committed by an interrupted flush, only removed from redis now
+		metricCounterFlush.Add(float64(len(deltas)/2), "skipped")
+	} else {
+		metricCounterFlush.Add(float64(len(fields)), "flushed")
+	}
+	return client.Del(ctx, c.batchKey, c.batchIDKey).Err()
+}
+
+// Start flushes the deltas every FlushInterval in the background.
+func (c *Counter) Start() {
+	c.wg.Add(1)
+	threading.GoSafe(func() {
+		defer c.wg.Done()
+
+		ticker := time.NewTicker(c.conf.FlushInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-c.done:
+				return
+			case <-ticker.C:
+				if err := c.FlushCtx(context.Background()); err != nil {
+					logx.Errorf("gormc: failed to flush counter %s: %v", c.conf.Prefix, err)
+				}
+			}
+		}
+	})
+}
+
+// Stop stops flushing periodically and flushes the remaining deltas.
+func (c *Counter) Stop() {
+	c.stopOnce.Do(func() {
+		close(c.done)
+		c.wg.Wait()
+		if err := c.FlushCtx(context.Background()); err != nil {
+			logx.Errorf("gormc: failed to flush counter %s: %v", c.conf.Prefix, err)
+		}
+	})
+}
+
+func (c *Counter) totalKey(field string) string {
+	return c.tag + field
+}
@@ -0,0 +1,148 @@
+package gormc_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/huof6829/gorm-zero/gormc"
+)
+
+type TestPost struct {
+	ID    int64 `gorm:"primaryKey"`
+	Views int64 `gorm:"column:views"`
+}
+
+func (TestPost) TableName() string {
+	return "posts"
+}
+
+func TestCounter(t *testing.T) {
+	db := setupTestDB(t)
+	mr, cache := setupTestCache(t)
+	ctx := context.Background()
+	if err := db.AutoMigrate(&TestPost{}); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+	db.Create(&TestPost{ID: 1, Views: 10})
+
+	conf := gormc.CounterConf{Table: "posts", Column: "views", Prefix: "counter:post:views:"}
+	counter, err := gormc.NewCounter(db, cache, conf)
+	if err != nil {
+		t.Fatalf("NewCounter failed: %v", err)
+	}
+
+	for i := 0; i < 3; i++ {
+		if _, err := counter.IncrCtx(ctx, 1, 1); err != nil {
+			t.Fatalf("IncrCtx failed: %v", err)
+		}
+	}
+	if total, err := counter.GetCtx(ctx, 1); err != nil || total != 13 {
+		t.Fatalf("Expected 13, got %d (%v)", total, err)
+	}
+
+	// 缓存的总数过期后，从数据库加上未刷新的增量重新加载
+	mr.Del("{counter:post:views:}1")
+	if total, err := counter.GetCtx(ctx, 1); err != nil || total != 13 {
+		t.Fatalf("Expected 13 after reload, got %d (%v)", total, err)
+	}
+
+	if err := counter.FlushCtx(ctx); err != nil {
+		t.Fatalf("FlushCtx failed: %v", err)
+	}
+	var post TestPost
+	db.First(&post, 1)
+	if post.Views != 13 {
+		t.Errorf("Expected 13 views in the database, got %d", post.Views)
+	}
+
+	// 新实例（模拟重启）接着计数，已刷新的增量不会重复写入
+	counter, _ = gormc.NewCounter(db, cache, conf)
+	if total, err := counter.IncrCtx(ctx, 1, 2); err != nil || total != 15 {
+		t.Fatalf("Expected 15, got %d (%v)", total, err)
+	}
+	counter.Start()
+	counter.Stop()
+	db.First(&post, 1)
+	if post.Views != 15 {
+		t.Errorf("Expected 15 views in the database after Stop, got %d", post.Views)
+	}
+
+	if _, err := counter.GetCtx(ctx, 2); !errors.Is(err, gormc.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for missing rows, got %v", err)
+	}
+}
+
+func TestCounter_ResumeFailedFlush(t *testing.T) {
+	db := setupTestDB(t)
+	mr, cache := setupTestCache(t)
+	ctx := context.Background()
+	if err := db.AutoMigrate(&TestPost{}); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+	db.Create(&TestPost{ID: 1, Views: 0})
+
+	counter, _ := gormc.NewCounter(db, cache, gormc.CounterConf{
+		Table: "posts", Column: "views", Prefix: "counter:post:views:",
+	})
+	// 上次刷新中断留下的增量
+	mr.HSet("{counter:post:views:}flushing", "1", "5")
+	if _, err := counter.IncrCtx(ctx, 1, 1); err != nil {
+		t.Fatalf("IncrCtx failed: %v", err)
+	}
+
+	if err := counter.FlushCtx(ctx); err != nil {
+		t.Fatalf("FlushCtx failed: %v", err)
+	}
+	if err := counter.FlushCtx(ctx); err != nil {
+		t.Fatalf("FlushCtx failed: %v", err)
+	}
+	var post TestPost
+	db.First(&post, 1)
+	if post.Views != 6 {
+		t.Errorf("Expected 6 views, got %d", post.Views)
+	}
+}
+
+func TestCounter_IdempotentFlush(t *testing.T) {
+	db := setupTestDB(t)
+	mr, cache := setupTestCache(t)
+	ctx := context.Background()
+	if err := db.AutoMigrate(&TestPost{}); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+	if err := db.Table("counter_batches").AutoMigrate(&gormc.CounterBatch{}); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+	db.Create(&TestPost{ID: 1, Views: 0})
+
+	counter, _ := gormc.NewCounter(db, cache, gormc.CounterConf{
+		Table: "posts", Column: "views", Prefix: "counter:post:views:", BatchTable: "counter_batches",
+	})
+	// 上次刷新已提交但没从 redis 删除的批次，不会重复写入
+	mr.HSet("{counter:post:views:}batch", "1", "5")
+	mr.Set("{counter:post:views:}batch:id", "committed")
+	db.Table("counter_batches").Create(&gormc.CounterBatch{ID: "committed"})
+	if _, err := counter.IncrCtx(ctx, 1, 1); err != nil {
+		t.Fatalf("IncrCtx failed: %v", err)
+	}
+
+	if err := counter.FlushCtx(ctx); err != nil {
+		t.Fatalf("FlushCtx failed: %v", err)
+	}
+	var post TestPost
+	db.First(&post, 1)
+	if post.Views != 1 {
+		t.Errorf("Expected 1 view, got %d", post.Views)
+	}
+	if mr.Exists("{counter:post:views:}batch") {
+		t.Error("Expected the batch to be removed")
+	}
+
+	// 每个批次记录一行
+	var batches int64
+	db.Table("counter_batches").Count(&batches)
+	if batches != 2 {
+		t.Errorf("Expected 2 recorded batches, got %d", batches)
+	}
+}
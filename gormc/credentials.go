@@ -0,0 +1,178 @@
+package gormc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Credentials are the username and password to connect with.
+type Credentials struct {
+	Username string
+	Password string
+}
+
+// CredentialProvider provides the credentials of the new connections to a database or redis.
+// It is called whenever a connection is established, so a rotated secret is picked up by the new connections
+// while the pooled ones keep working until they are closed, e.g. by ConnMaxLifetime.
+// An empty Username keeps the configured one.
+type CredentialProvider interface {
+	Credentials(ctx context.Context) (Credentials, error)
+}
+
+// CredentialProviderFunc is a function implementing CredentialProvider, e.g. reading a secret store.
+type CredentialProviderFunc func(ctx context.Context) (Credentials, error)
+
+// Credentials calls f.
+func (f CredentialProviderFunc) Credentials(ctx context.Context) (Credentials, error) {
+	return f(ctx)
+}
+
+// CredentialConf selects where the credentials are read from, instead of the plaintext Username and Password.
+// At most one of Provider, the env vars and the files can be set.
+type CredentialConf struct {
+	Provider     string `json:",optional"` // RegisterCredentialProvider 注册的提供者名称，如 vault
+	UsernameEnv  string `json:",optional"` // 读取用户名的环境变量
+	PasswordEnv  string `json:",optional"` // 读取密码的环境变量
+	UsernameFile string `json:",optional"` // 读取用户名的文件，如 Kubernetes 挂载的 secret
+	PasswordFile string `json:",optional"` // 读取密码的文件
+}
+
+var (
+	credentialProviders   = make(map[string]CredentialProvider)
+	credentialProvidersMu sync.RWMutex
+)
+
+// RegisterCredentialProvider registers p as name, to be selected by CredentialConf.Provider.
+func RegisterCredentialProvider(name string, p CredentialProvider) {
+	credentialProvidersMu.Lock()
+	defer credentialProvidersMu.Unlock()
+	credentialProviders[name] = p
+}
+
+// NewProvider returns the CredentialProvider configured by c, nil if c is empty.
+func (c CredentialConf) NewProvider() (CredentialProvider, error) {
+	env := len(c.UsernameEnv) > 0 || len(c.PasswordEnv) > 0
+	file := len(c.UsernameFile) > 0 || len(c.PasswordFile) > 0
+	sources := 0
+	for _, set := range []bool{len(c.Provider) > 0, env, file} {
+		if set {
+			sources++
+		}
+	}
+	if sources > 1 {
+		return nil, errors.New("credential config error: Provider, the env vars and the files are mutually exclusive")
+	}
+
+	switch {
+	case len(c.Provider) > 0:
+		credentialProvidersMu.RLock()
+		p, ok := credentialProviders[c.Provider]
+		credentialProvidersMu.RUnlock()
+		if !ok {
+			return nil, fmt.Errorf("credential config error: provider %q is not registered", c.Provider)
+		}
+		return p, nil
+	case env:
+		return EnvCredentials(c.UsernameEnv, c.PasswordEnv), nil
+	case file:
+		return FileCredentials(c.UsernameFile, c.PasswordFile), nil
+	default:
+		return nil, nil
+	}
+}
+
+// StaticCredentials returns a CredentialProvider of fixed credentials.
+func StaticCredentials(username, password string) CredentialProvider {
+	return CredentialProviderFunc(func(context.Context) (Credentials, error) {
+		return Credentials{Username: username, Password: password}, nil
+	})
+}
+
+// EnvCredentials returns a CredentialProvider reading the env vars usernameEnv and passwordEnv,
+// either may be empty to leave the value empty. The env vars are read on every call.
+func EnvCredentials(usernameEnv, passwordEnv string) CredentialProvider {
+	return CredentialProviderFunc(func(context.Context) (Credentials, error) {
+		var creds Credentials
+		if len(usernameEnv) > 0 {
+			creds.Username = os.Getenv(usernameEnv)
+		}
+		if len(passwordEnv) > 0 {
+			password, ok := os.LookupEnv(passwordEnv)
+			if !ok {
+				return Credentials{}, fmt.Errorf("gormc: env var %s is not set", passwordEnv)
+			}
+			creds.Password = password
+		}
+		return creds, nil
+	})
+}
+
+// FileCredentials returns a CredentialProvider reading the files usernameFile and passwordFile,
+// either may be empty to leave the value empty. The files are read on every call, so a secret
+// rewritten in place, e.g. mounted by Kubernetes, is picked up. Trailing newlines are trimmed.
+func FileCredentials(usernameFile, passwordFile string) CredentialProvider {
+	return CredentialProviderFunc(func(context.Context) (Credentials, error) {
+		var creds Credentials
+		var err error
+		if creds.Username, err = readSecret(usernameFile); err != nil {
+			return Credentials{}, err
+		}
+		if creds.Password, err = readSecret(passwordFile); err != nil {
+			return Credentials{}, err
+		}
+		return creds, nil
+	})
+}
+
+func readSecret(path string) (string, error) {
+	if len(path) == 0 {
+		return "", nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+// CachedCredentials returns a CredentialProvider caching the credentials of p for ttl,
+// e.g. to spare a remote secret store when the pools open many connections.
+// Errors are not cached.
+func CachedCredentials(p CredentialProvider, ttl time.Duration) CredentialProvider {
+	var (
+		mu      sync.Mutex
+		creds   Credentials
+		expires time.Time
+	)
+	return CredentialProviderFunc(func(ctx context.Context) (Credentials, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if time.Now().Before(expires) {
+			return creds, nil
+		}
+		fresh, err := p.Credentials(ctx)
+		if err != nil {
+			return Credentials{}, err
+		}
+		creds, expires = fresh, time.Now().Add(ttl)
+		return creds, nil
+	})
+}
+
+// ResolveCredentials returns the credentials of p, with the username defaulting to username.
+// It is used by the database configs to apply a CredentialProvider to each new connection.
+func ResolveCredentials(ctx context.Context, p CredentialProvider, username string) (Credentials, error) {
+	creds, err := p.Credentials(ctx)
+	if err != nil {
+		return Credentials{}, err
+	}
+	if len(creds.Username) == 0 {
+		creds.Username = username
+	}
+	return creds, nil
+}
@@ -0,0 +1,132 @@
+package gormc_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/huof6829/gorm-zero/gormc"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestCredentialConf_NewProvider(t *testing.T) {
+	ctx := context.Background()
+
+	// 未配置时没有提供者
+	if p, err := (gormc.CredentialConf{}).NewProvider(); p != nil || err != nil {
+		t.Errorf("Expected no provider, got %v, %v", p, err)
+	}
+
+	// 多个来源互斥
+	_, err := gormc.CredentialConf{PasswordEnv: "PW", PasswordFile: "pw"}.NewProvider()
+	if err == nil {
+		t.Error("Expected an error for more than one source")
+	}
+	if _, err := (gormc.CredentialConf{Provider: "missing"}).NewProvider(); err == nil {
+		t.Error("Expected an error for an unregistered provider")
+	}
+
+	// 环境变量每次读取
+	t.Setenv("GORMC_TEST_USER", "app")
+	t.Setenv("GORMC_TEST_PASSWORD", "secret1")
+	p, err := gormc.CredentialConf{UsernameEnv: "GORMC_TEST_USER", PasswordEnv: "GORMC_TEST_PASSWORD"}.NewProvider()
+	if err != nil {
+		t.Fatalf("NewProvider failed: %v", err)
+	}
+	t.Setenv("GORMC_TEST_PASSWORD", "secret2")
+	creds, err := p.Credentials(ctx)
+	if err != nil || creds != (gormc.Credentials{Username: "app", Password: "secret2"}) {
+		t.Errorf("Expected the rotated password, got %+v, %v", creds, err)
+	}
+
+	// 注册的提供者，用户名为空时使用配置的用户名
+	gormc.RegisterCredentialProvider("test-store", gormc.StaticCredentials("", "stored"))
+	p, err = gormc.CredentialConf{Provider: "test-store"}.NewProvider()
+	if err != nil {
+		t.Fatalf("NewProvider failed: %v", err)
+	}
+	creds, err = gormc.ResolveCredentials(ctx, p, "configured")
+	if err != nil || creds != (gormc.Credentials{Username: "configured", Password: "stored"}) {
+		t.Errorf("Expected the configured username, got %+v, %v", creds, err)
+	}
+}
+
+func TestFileCredentials(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "password")
+	if err := os.WriteFile(path, []byte("secret1\n"), 0o600); err != nil {
+		t.Fatalf("Failed to write secret: %v", err)
+	}
+
+	p := gormc.FileCredentials("", path)
+	creds, err := p.Credentials(ctx)
+	if err != nil || creds.Password != "secret1" {
+		t.Errorf("Expected secret1, got %q, %v", creds.Password, err)
+	}
+
+	// 文件被改写后读到新密码
+	if err := os.WriteFile(path, []byte("secret2\n"), 0o600); err != nil {
+		t.Fatalf("Failed to write secret: %v", err)
+	}
+	creds, err = p.Credentials(ctx)
+	if err != nil || creds.Password != "secret2" {
+		t.Errorf("Expected secret2, got %q, %v", creds.Password, err)
+	}
+
+	// 缓存期内不重新读取
+	cached := gormc.CachedCredentials(p, time.Hour)
+	if creds, _ := cached.Credentials(ctx); creds.Password != "secret2" {
+		t.Errorf("Expected secret2, got %q", creds.Password)
+	}
+	if err := os.WriteFile(path, []byte("secret3\n"), 0o600); err != nil {
+		t.Fatalf("Failed to write secret: %v", err)
+	}
+	if creds, _ := cached.Credentials(ctx); creds.Password != "secret2" {
+		t.Errorf("Expected the cached secret2, got %q", creds.Password)
+	}
+
+	if err := os.Remove(path); err != nil {
+		t.Fatalf("Failed to remove secret: %v", err)
+	}
+	if _, err := p.Credentials(ctx); err == nil {
+		t.Error("Expected an error for a missing file")
+	}
+}
+
+func TestRedisCredentials_Rotation(t *testing.T) {
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("Failed to start miniredis: %v", err)
+	}
+	defer mr.Close()
+	mr.RequireAuth("secret1")
+
+	path := filepath.Join(t.TempDir(), "password")
+	if err := os.WriteFile(path, []byte("secret1"), 0o600); err != nil {
+		t.Fatalf("Failed to write secret: %v", err)
+	}
+	client, err := gormc.NewRedisClient(gormc.RedisConfig{Addr: mr.Addr(),
+		Credentials: gormc.CredentialConf{PasswordFile: path}})
+	if err != nil {
+		t.Fatalf("NewRedisClient failed: %v", err)
+	}
+	defer client.(*redis.Client).Close()
+	ctx := context.Background()
+
+	// 轮换密码后，已有连接继续可用，新建的连接使用新密码
+	mr.RequireAuth("secret2")
+	if err := os.WriteFile(path, []byte("secret2"), 0o600); err != nil {
+		t.Fatalf("Failed to write secret: %v", err)
+	}
+	// 占住比启动时建立的连接更多的连接，保证至少有一个是新建的
+	for i := 0; i < 4; i++ {
+		conn := client.(*redis.Client).Conn()
+		defer conn.Close()
+		if err := conn.Ping(ctx).Err(); err != nil {
+			t.Errorf("Expected connection %d to work after the rotation, got %v", i, err)
+		}
+	}
+}
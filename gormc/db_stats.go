@@ -0,0 +1,107 @@
+package gormc
+
+import (
+	"database/sql"
+	"sync"
+	"time"
+
+	"github.com/zeromicro/go-zero/core/threading"
+	"gorm.io/gorm"
+)
+
+// defaultStatsInterval is how often the pool stats are published by default.
+const defaultStatsInterval = 15 * time.Second
+
+// DBStatsExporter publishes the connection pool stats of a database as metrics labeled by its name.
+type DBStatsExporter struct {
+	name     string
+	db       *sql.DB
+	interval time.Duration
+	mu       sync.Mutex
+	last     sql.DBStats
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+// NewDBStatsExporter returns a DBStatsExporter of db named name, interval defaults to 15s.
+// It makes a go-zero service.Service, or call ExportDBStats to run it in the background.
+func NewDBStatsExporter(name string, db *gorm.DB, interval time.Duration) (*DBStatsExporter, error) {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+	if interval <= 0 {
+		interval = defaultStatsInterval
+	}
+	return &DBStatsExporter{
+		name:     name,
+		db:       sqlDB,
+		interval: interval,
+		done:     make(chan struct{}),
+	}, nil
+}
+
+// ExportDBStats publishes the pool stats of db named name every interval in the background until Stop.
+func ExportDBStats(name string, db *gorm.DB, interval time.Duration) (*DBStatsExporter, error) {
+	e, err := NewDBStatsExporter(name, db, interval)
+	if err != nil {
+		return nil, err
+	}
+	threading.GoSafe(e.Start)
+	return e, nil
+}
+
+// Start publishes the stats every interval, it blocks until Stop.
+func (e *DBStatsExporter) Start() {
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+
+	e.Collect()
+	for {
+		select {
+		case <-ticker.C:
+			e.Collect()
+		case <-e.done:
+			return
+		}
+	}
+}
+
+// Stop stops publishing the stats.
+func (e *DBStatsExporter) Stop() {
+	e.stopOnce.Do(func() {
+		close(e.done)
+	})
+}
+
+// Collect publishes the current stats and returns them.
+func (e *DBStatsExporter) Collect() sql.DBStats {
+	stats := e.db.Stats()
+
+	e.mu.Lock()
+	last := e.last
+	e.last = stats
+	e.mu.Unlock()
+
+	metricDBConns.Set(float64(stats.OpenConnections), e.name, "open")
+	metricDBConns.Set(float64(stats.InUse), e.name, "in_use")
+	metricDBConns.Set(float64(stats.Idle), e.name, "idle")
+	metricDBConns.Set(float64(stats.MaxOpenConnections), e.name, "max_open")
+	// the counts of sql.DBStats are cumulative, publish the increments since the last collection
+	if delta := stats.WaitCount - last.WaitCount; delta > 0 {
+		metricDBWaits.Add(float64(delta), e.name)
+	}
+	if delta := stats.WaitDuration - last.WaitDuration; delta > 0 {
+		metricDBWaitSeconds.Add(delta.Seconds(), e.name)
+	}
+	if delta := stats.MaxIdleClosed - last.MaxIdleClosed; delta > 0 {
+		metricDBClosed.Add(float64(delta), e.name, "max_idle")
+	}
+	if delta := stats.MaxIdleTimeClosed - last.MaxIdleTimeClosed; delta > 0 {
+		metricDBClosed.Add(float64(delta), e.name, "max_idle_time")
+	}
+	if delta := stats.MaxLifetimeClosed - last.MaxLifetimeClosed; delta > 0 {
+		metricDBClosed.Add(float64(delta), e.name, "max_lifetime")
+	}
+	return stats
+}
@@ -0,0 +1,35 @@
+package gormc_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/huof6829/gorm-zero/gormc"
+)
+
+func TestDBStatsExporter(t *testing.T) {
+	db := setupTestDB(t)
+
+	exporter, err := gormc.ExportDBStats("test", db, 10*time.Millisecond)
+	if err != nil {
+		t.Fatalf("ExportDBStats failed: %v", err)
+	}
+	defer exporter.Stop()
+
+	// 占用一个连接后，统计应该能看到
+	sqlDB, _ := db.DB()
+	conn, err := sqlDB.Conn(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to get a connection: %v", err)
+	}
+	stats := exporter.Collect()
+	conn.Close()
+	if stats.InUse != 1 || stats.MaxOpenConnections != 1 {
+		t.Errorf("Expected 1 connection in use of 1, got %d of %d", stats.InUse, stats.MaxOpenConnections)
+	}
+
+	// Stop 可以重复调用
+	exporter.Stop()
+	exporter.Stop()
+}
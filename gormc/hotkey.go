@@ -0,0 +1,273 @@
+package gormc
+
+import (
+	"hash/maphash"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/zeromicro/go-zero/core/collection"
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+const (
+	hotKeySketchDepth = 4
+	hotKeySketchWidth = 4096
+)
+
+type (
+	// HotKeyConf is the configuration of hot key detection.
+	HotKeyConf struct {
+		Enabled bool `json:",optional"`
+		// TopN is the number of hot keys tracked and reported by HotKeys.
+		TopN int `json:",default=20"`
+		// Threshold is the estimated number of reads within Window for a key to be hot.
+		Threshold int64 `json:",default=1000"`
+		// Window is the decay period, all counters are halved every Window.
+		Window time.Duration `json:",default=1m"`
+		// LocalCache promotes hot keys into a short-lived in-process cache.
+		// Writes and deletes on this instance drop the local copy, other instances may serve
+		// the old value until LocalExpiry elapses, so keep it short.
+		LocalCache    bool          `json:",optional"`
+		LocalExpiry   time.Duration `json:",default=1s"`
+		LocalCapacity int           `json:",default=1000"`
+	}
+
+	// HotKey is a key reported by hot key detection.
+	HotKey struct {
+		Key   string
+		Count int64 // estimated number of reads in the current window
+	}
+
+	// hotKeyDetector counts key reads with a count-min sketch of atomic counters and keeps the top-N keys.
+	// The reads of the tracked keys and of the keys below the top-N don't take any lock,
+	// mu is only taken when a key enters the top-N and on decay.
+	hotKeyDetector struct {
+		conf      HotKeyConf
+		seeds     [hotKeySketchDepth]maphash.Seed
+		sketch    [hotKeySketchDepth][]atomic.Uint32
+		mu        sync.Mutex
+		top       atomic.Pointer[map[string]*atomic.Int64] // copied on write under mu
+		admit     atomic.Int64                             // count to beat to enter the top-N, 0 until it is full
+		lastDecay atomic.Int64                             // unix nanoseconds
+		local     *collection.Cache
+	}
+)
+
+// WithHotKeyDetection enables hot key detection on a RedisCache.
+func WithHotKeyDetection(conf HotKeyConf) RedisCacheOption {
+	return func(c *RedisCache) {
+		c.hotKeys = newHotKeyDetector(conf)
+	}
+}
+
+func (c HotKeyConf) withDefaults() HotKeyConf {
+	if c.TopN <= 0 {
+		c.TopN = 20
+	}
+	if c.Threshold <= 0 {
+		c.Threshold = 1000
+	}
+	if c.Window <= 0 {
+		c.Window = time.Minute
+	}
+	if c.LocalExpiry <= 0 {
+		c.LocalExpiry = time.Second
+	}
+	if c.LocalCapacity <= 0 {
+		c.LocalCapacity = 1000
+	}
+	return c
+}
+
+func newHotKeyDetector(conf HotKeyConf) *hotKeyDetector {
+	conf = conf.withDefaults()
+	d := &hotKeyDetector{conf: conf}
+	d.top.Store(&map[string]*atomic.Int64{})
+	d.lastDecay.Store(time.Now().UnixNano())
+	for i := range d.sketch {
+		d.seeds[i] = maphash.MakeSeed()
+		d.sketch[i] = make([]atomic.Uint32, hotKeySketchWidth)
+	}
+
+	if conf.LocalCache {
+		local, err := collection.NewCache(conf.LocalExpiry, collection.WithLimit(conf.LocalCapacity),
+			collection.WithName("gormc-hotkey"))
+		if err != nil {
+			logx.Errorf("gormc: hot key local cache disabled: %v", err)
+		} else {
+			d.local = local
+		}
+	}
+
+	return d
+}
+
+// record counts a read of key and reports whether the key is hot.
+func (d *hotKeyDetector) record(key string) bool {
+	d.decayIfNeeded()
+
+	var estimate uint32
+	for i := range d.sketch {
+		count := d.sketch[i][maphash.String(d.seeds[i], key)%hotKeySketchWidth].Add(1)
+		if i == 0 || count < estimate {
+			estimate = count
+		}
+	}
+
+	count := int64(estimate)
+	if tracked, ok := (*d.top.Load())[key]; ok {
+		tracked.Store(count)
+	} else if count > d.admit.Load() {
+		d.enter(key, count)
+	}
+	return count >= d.conf.Threshold
+}
+
+// enter adds key into the top-N, evicting the coldest key if it is full.
+func (d *hotKeyDetector) enter(key string, count int64) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	top := *d.top.Load()
+	if _, ok := top[key]; ok {
+		return
+	}
+	next := make(map[string]*atomic.Int64, len(top)+1)
+	for k, v := range top {
+		next[k] = v
+	}
+	if len(next) >= d.conf.TopN {
+		minKey, minCount := minTop(next)
+		if count <= minCount {
+			d.admit.Store(minCount)
+			return
+		}
+		delete(next, minKey)
+	}
+	tracked := new(atomic.Int64)
+	tracked.Store(count)
+	next[key] = tracked
+
+	d.top.Store(&next)
+	if len(next) >= d.conf.TopN {
+		_, minCount := minTop(next)
+		d.admit.Store(minCount)
+	}
+}
+
+// decayIfNeeded halves all counters once per window, so that keys cool down.
+// The top-N is exported before decaying, as the reads of the window that ends.
+func (d *hotKeyDetector) decayIfNeeded() {
+	last := d.lastDecay.Load()
+	now := time.Now().UnixNano()
+	if time.Duration(now-last) < d.conf.Window || !d.lastDecay.CompareAndSwap(last, now) {
+		return
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	top := *d.top.Load()
+	exportHotKeys(top)
+
+	// the increments racing with the halving may be lost, the sketch is an estimate anyway
+	for i := range d.sketch {
+		for j := range d.sketch[i] {
+			counter := &d.sketch[i][j]
+			counter.Store(counter.Load() >> 1)
+		}
+	}
+	next := make(map[string]*atomic.Int64, len(top))
+	for key, tracked := range top {
+		if count := tracked.Load() >> 1; count > 0 {
+			tracked.Store(count)
+			next[key] = tracked
+		}
+	}
+	d.top.Store(&next)
+	d.admit.Store(0)
+	if len(next) >= d.conf.TopN {
+		_, minCount := minTop(next)
+		d.admit.Store(minCount)
+	}
+}
+
+func minTop(top map[string]*atomic.Int64) (string, int64) {
+	var minKey string
+	minCount := int64(-1)
+	for key, tracked := range top {
+		if count := tracked.Load(); minCount < 0 || count < minCount {
+			minKey, minCount = key, count
+		}
+	}
+	return minKey, minCount
+}
+
+// exportHotKeys replaces the keys of gormc_cache_hot_key_reads with top,
+// so that the number of series is bounded by TopN.
+func exportHotKeys(top map[string]*atomic.Int64) {
+	metricHotKeyReads.Reset()
+	for key, tracked := range top {
+		metricHotKeyReads.WithLabelValues(key).Set(float64(tracked.Load()))
+	}
+}
+
+// hotKeys returns the tracked keys ordered by count desc.
+func (d *hotKeyDetector) hotKeys() []HotKey {
+	top := *d.top.Load()
+	keys := make([]HotKey, 0, len(top))
+	for key, tracked := range top {
+		keys = append(keys, HotKey{Key: key, Count: tracked.Load()})
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].Count == keys[j].Count {
+			return keys[i].Key < keys[j].Key
+		}
+		return keys[i].Count > keys[j].Count
+	})
+	return keys
+}
+
+// getLocal returns the raw value of key from the local cache.
+func (d *hotKeyDetector) getLocal(key string) ([]byte, bool) {
+	if d.local == nil {
+		return nil, false
+	}
+	val, ok := d.local.Get(key)
+	if !ok {
+		return nil, false
+	}
+	data, ok := val.([]byte)
+	return data, ok
+}
+
+// promote keeps the raw value of a hot key in the local cache.
+func (d *hotKeyDetector) promote(key string, data []byte) {
+	if d.local == nil {
+		return
+	}
+	d.local.Set(key, data)
+	metricHotKeys.Inc("promoted")
+}
+
+// evict drops the local copies of keys.
+func (d *hotKeyDetector) evict(keys ...string) {
+	if d.local == nil {
+		return
+	}
+	for _, key := range keys {
+		d.local.Del(key)
+	}
+}
+
+// HotKeys returns the top-N hot keys ordered by estimated reads in the current window.
+// It returns nil if hot key detection is not enabled.
+func (c *RedisCache) HotKeys() []HotKey {
+	if c.hotKeys == nil {
+		return nil
+	}
+	return c.hotKeys.hotKeys()
+}
@@ -0,0 +1,147 @@
+package gormc_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/huof6829/gorm-zero/gormc"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestRedisCache_HotKeys(t *testing.T) {
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("Failed to start miniredis: %v", err)
+	}
+	defer mr.Close()
+
+	cache, err := gormc.NewRedisCache(gormc.RedisConfig{
+		Addr: mr.Addr(),
+		HotKey: gormc.HotKeyConf{
+			Enabled:     true,
+			TopN:        2,
+			Threshold:   3,
+			LocalCache:  true,
+			LocalExpiry: time.Minute,
+		},
+	}, time.Minute)
+	if err != nil {
+		t.Fatalf("Failed to create redis cache: %v", err)
+	}
+	defer cache.Close()
+
+	ctx := context.Background()
+	for _, key := range []string{"user:1", "user:2", "user:3"} {
+		if err := cache.SetCtx(ctx, key, key); err != nil {
+			t.Fatalf("SetCtx failed: %v", err)
+		}
+	}
+
+	var val string
+	for i := 0; i < 5; i++ {
+		if err := cache.GetCtx(ctx, "user:1", &val); err != nil {
+			t.Fatalf("GetCtx failed: %v", err)
+		}
+	}
+	_ = cache.GetCtx(ctx, "user:2", &val)
+	_ = cache.GetCtx(ctx, "user:2", &val)
+	_ = cache.GetCtx(ctx, "user:3", &val)
+
+	hotKeys := cache.HotKeys()
+	if len(hotKeys) != 2 || hotKeys[0].Key != "user:1" || hotKeys[0].Count != 5 {
+		t.Fatalf("unexpected hot keys: %+v", hotKeys)
+	}
+
+	// 热点 key 已进入本地缓存，直接修改 Redis 不影响读取
+	mr.Set("user:1", `"changed"`)
+	if err := cache.GetCtx(ctx, "user:1", &val); err != nil || val != "user:1" {
+		t.Fatalf("Expected local cached value user:1, got %q (%v)", val, err)
+	}
+
+	// 本实例写入会清除本地副本
+	if err := cache.SetCtx(ctx, "user:1", "updated"); err != nil {
+		t.Fatalf("SetCtx failed: %v", err)
+	}
+	if err := cache.GetCtx(ctx, "user:1", &val); err != nil || val != "updated" {
+		t.Fatalf("Expected updated value, got %q (%v)", val, err)
+	}
+}
+
+func TestRedisCache_HotKeysConcurrentAndExported(t *testing.T) {
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("Failed to start miniredis: %v", err)
+	}
+	defer mr.Close()
+
+	cache, err := gormc.NewRedisCache(gormc.RedisConfig{
+		Addr: mr.Addr(),
+		HotKey: gormc.HotKeyConf{
+			Enabled:   true,
+			TopN:      2,
+			Threshold: 1000,
+			Window:    200 * time.Millisecond,
+		},
+	}, time.Minute)
+	if err != nil {
+		t.Fatalf("Failed to create redis cache: %v", err)
+	}
+	defer cache.Close()
+
+	ctx := context.Background()
+	for _, key := range []string{"order:1", "order:2", "order:3"} {
+		if err := cache.SetCtx(ctx, key, key); err != nil {
+			t.Fatalf("SetCtx failed: %v", err)
+		}
+	}
+
+	// 并发读取，热点统计不加全局锁也不能丢失 top-N
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var val string
+			for j := 0; j < 20; j++ {
+				_ = cache.GetCtx(ctx, "order:1", &val)
+				if j%2 == 0 {
+					_ = cache.GetCtx(ctx, "order:2", &val)
+				}
+				if j%10 == 0 {
+					_ = cache.GetCtx(ctx, "order:3", &val)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	hotKeys := cache.HotKeys()
+	if len(hotKeys) != 2 || hotKeys[0].Key != "order:1" || hotKeys[1].Key != "order:2" {
+		t.Fatalf("unexpected hot keys: %+v", hotKeys)
+	}
+
+	// 窗口结束时导出 top-N 到 gormc_cache_hot_key_reads
+	time.Sleep(250 * time.Millisecond)
+	var val string
+	_ = cache.GetCtx(ctx, "order:1", &val)
+
+	families, err := prometheus.DefaultGatherer.Gather()
+	if err != nil {
+		t.Fatalf("Failed to gather metrics: %v", err)
+	}
+	exported := make(map[string]float64)
+	for _, family := range families {
+		if family.GetName() != "gormc_cache_hot_key_reads" {
+			continue
+		}
+		for _, m := range family.GetMetric() {
+			exported[m.GetLabel()[0].GetValue()] = m.GetGauge().GetValue()
+		}
+	}
+	if len(exported) != 2 || exported["order:1"] < 160 || exported["order:2"] < 80 {
+		t.Fatalf("unexpected exported hot keys: %v", exported)
+	}
+}
@@ -0,0 +1,126 @@
+package gormc
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/zeromicro/go-zero/core/logx"
+	"github.com/zeromicro/go-zero/core/proc"
+	"github.com/zeromicro/go-zero/core/threading"
+)
+
+// drainTimeout is how long Close waits for the async work in flight.
+const drainTimeout = 5 * time.Second
+
+// HealthStatus is the result of a health check, a nil error means the backend is healthy.
+type HealthStatus struct {
+	DB    error
+	Redis error
+}
+
+// Err returns the errors of the unhealthy backends, nil if both are healthy.
+func (h HealthStatus) Err() error {
+	return errors.Join(h.DB, h.Redis)
+}
+
+// Health pings the database and the redis deployments used by cc.
+func (cc CachedConn) Health(ctx context.Context) HealthStatus {
+	var status HealthStatus
+	if sqlDB, err := cc.db.DB(); err != nil {
+		status.DB = err
+	} else {
+		status.DB = sqlDB.PingContext(ctx)
+	}
+	status.Redis = cc.cache.Ping(ctx)
+	return status
+}
+
+// Ping pings the database and redis, it returns the errors of the unhealthy ones.
+func (cc CachedConn) Ping(ctx context.Context) error {
+	return cc.Health(ctx).Err()
+}
+
+// Close waits for the async work in flight, then closes redis and the database.
+// The database and the RedisCache are usually shared by all the models, so close once on shutdown.
+func (cc CachedConn) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), drainTimeout)
+	defer cancel()
+	return cc.CloseCtx(ctx)
+}
+
+// CloseCtx is Close, it stops waiting for the async work when ctx is done.
+// The plugins of the database implementing io.Closer, e.g. the replica pools, are closed with it.
+func (cc CachedConn) CloseCtx(ctx context.Context) error {
+	return errors.Join(cc.cache.CloseCtx(ctx), closeDB(cc.db))
+}
+
+// Start does nothing, it makes CachedConn a go-zero service.Service,
+// so that it can be added into a service.ServiceGroup and closed with Stop.
+func (cc CachedConn) Start() {
+}
+
+// Stop closes cc, it makes CachedConn a go-zero service.Service.
+func (cc CachedConn) Stop() {
+	if err := cc.Close(); err != nil {
+		logx.Errorf("gormc: failed to close: %v", err)
+	}
+}
+
+// CloseOnShutdown registers cc to be closed when the process receives a shutdown signal.
+func (cc CachedConn) CloseOnShutdown() {
+	proc.AddShutdownListener(cc.Stop)
+}
+
+// Ping pings the redis deployments, including the secondary while migrating.
+func (c *RedisCache) Ping(ctx context.Context) error {
+	err := c.primary().Ping(ctx).Err()
+	if secondary := c.secondary(); secondary != nil {
+		err = errors.Join(err, secondary.Ping(ctx).Err())
+	}
+	return err
+}
+
+// CloseCtx waits for the async work in flight until ctx is done, then closes the redis clients.
+// It is safe to be called more than once.
+func (c *RedisCache) CloseCtx(ctx context.Context) error {
+	c.closeOnce.Do(func() {
+		c.asyncMu.Lock()
+		c.closed = true
+		close(c.done)
+		metricCacheMode.Dec(c.Mode().String())
+		c.asyncMu.Unlock()
+
+		done := make(chan struct{})
+		go func() {
+			c.async.Wait()
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-ctx.Done():
+			logx.Errorf("gormc: closing with async work in flight: %v", ctx.Err())
+		}
+
+		c.closeErr = c.closeClients()
+	})
+	return c.closeErr
+}
+
+// goAsync runs fn in the background, Close waits for it.
+// It reports false without running fn if c is closed.
+func (c *RedisCache) goAsync(fn func()) bool {
+	c.asyncMu.Lock()
+	if c.closed {
+		c.asyncMu.Unlock()
+		return false
+	}
+	c.async.Add(1)
+	c.asyncMu.Unlock()
+
+	threading.GoSafe(func() {
+		defer c.async.Done()
+		fn()
+	})
+	return true
+}
@@ -0,0 +1,61 @@
+package gormc_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/huof6829/gorm-zero/gormc"
+	"github.com/zeromicro/go-zero/core/service"
+	"gorm.io/gorm"
+)
+
+// CachedConn 可以直接加入 go-zero 的 ServiceGroup
+var _ service.Service = gormc.CachedConn{}
+
+func TestCachedConn_Lifecycle(t *testing.T) {
+	db := setupTestDB(t)
+	mr, cache := setupTestCache(t)
+	cachedConn := gormc.NewConnWithCache(db, cache, gormc.WithShadowVerify(gormc.ShadowVerifyConf{SampleRate: 1}))
+	ctx := context.Background()
+	db.Create(&TestUser{ID: 1, Name: "Alice"})
+
+	if status := cachedConn.Health(ctx); status.Err() != nil {
+		t.Fatalf("Expected healthy backends, got %+v", status)
+	}
+
+	mr.SetError("LOADING")
+	if status := cachedConn.Health(ctx); status.Redis == nil || status.DB != nil {
+		t.Errorf("Expected redis to be unhealthy, got %+v", status)
+	}
+	mr.SetError("")
+
+	// 命中后触发异步校验，Close 等待其完成
+	var user TestUser
+	for i := 0; i < 2; i++ {
+		err := cachedConn.QueryRowIndexCtx(ctx, &user, "user:name:Alice", func(primary interface{}) string {
+			return "user:id:1"
+		}, func(conn *gorm.DB, v interface{}) (interface{}, error) {
+			return int64(1), conn.Where("name = ?", "Alice").Take(v).Error
+		}, func(conn *gorm.DB, v, primary interface{}) error {
+			return conn.Where("id = ?", primary).Take(v).Error
+		})
+		if err != nil {
+			t.Fatalf("QueryRowIndexCtx failed: %v", err)
+		}
+	}
+
+	if err := cachedConn.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if stats := cachedConn.ShadowVerifyStats(); stats.Verified+stats.Errors != 1 {
+		t.Errorf("Expected the async verification to be drained, got %+v", stats)
+	}
+	if status := cachedConn.Health(ctx); status.DB == nil || status.Redis == nil {
+		t.Errorf("Expected both backends to be closed, got %+v", status)
+	}
+
+	// 重复关闭是安全的
+	if err := cache.Close(); err != nil {
+		t.Errorf("Expected closing again to be a no-op, got %v", err)
+	}
+}
@@ -0,0 +1,114 @@
+package gormc
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/zeromicro/go-zero/core/metric"
+)
+
+const cacheNamespace = "gormc_cache"
+
+var (
+	metricHotKeys = metric.NewCounterVec(&metric.CounterVecOpts{
+		Namespace: cacheNamespace,
+		Subsystem: "hot_key",
+		Name:      "total",
+		Help:      "gormc hot key local cache events.",
+		Labels:    []string{"event"},
+	})
+
+	// metricHotKeyReads uses prometheus directly, the keys leaving the top-N must be removed
+	// to bound the number of series, which the go-zero metrics can't do.
+	metricHotKeyReads = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: cacheNamespace,
+		Subsystem: "hot_key",
+		Name:      "reads",
+		Help:      "gormc estimated reads of the top-N hot keys in the last window.",
+	}, []string{"key"})
+
+	metricBigValues = metric.NewCounterVec(&metric.CounterVecOpts{
+		Namespace: cacheNamespace,
+		Subsystem: "big_value",
+		Name:      "total",
+		Help:      "gormc cache values not cached because they exceed the max value size.",
+		Labels:    []string{"policy"},
+	})
+
+	metricCacheMode = metric.NewGaugeVec(&metric.GaugeVecOpts{
+		Namespace: cacheNamespace,
+		Subsystem: "mode",
+		Name:      "caches",
+		Help:      "gormc number of caches in each mode.",
+		Labels:    []string{"mode"},
+	})
+
+	metricShadowVerify = metric.NewCounterVec(&metric.CounterVecOpts{
+		Namespace: cacheNamespace,
+		Subsystem: "shadow_verify",
+		Name:      "total",
+		Help:      "gormc shadow verification results of cache hits.",
+		Labels:    []string{"result"},
+	})
+
+	metricBloomRejects = metric.NewCounterVec(&metric.CounterVecOpts{
+		Namespace: cacheNamespace,
+		Subsystem: "bloom",
+		Name:      "rejects_total",
+		Help:      "gormc lookups rejected by the bloom filter.",
+	})
+
+	metricCounterFlush = metric.NewCounterVec(&metric.CounterVecOpts{
+		Namespace: cacheNamespace,
+		Subsystem: "counter_flush",
+		Name:      "total",
+		Help:      "gormc counter rows flushed into the database.",
+		Labels:    []string{"result"},
+	})
+
+	metricMigration = metric.NewCounterVec(&metric.CounterVecOpts{
+		Namespace: cacheNamespace,
+		Subsystem: "migration",
+		Name:      "total",
+		Help:      "gormc cache migration events.",
+		Labels:    []string{"event"},
+	})
+)
+
+const dbNamespace = "gormc_db"
+
+var (
+	metricDBConns = metric.NewGaugeVec(&metric.GaugeVecOpts{
+		Namespace: dbNamespace,
+		Subsystem: "pool",
+		Name:      "conns",
+		Help:      "gormc database connections in each state.",
+		Labels:    []string{"db", "state"},
+	})
+
+	metricDBWaits = metric.NewCounterVec(&metric.CounterVecOpts{
+		Namespace: dbNamespace,
+		Subsystem: "pool",
+		Name:      "wait_total",
+		Help:      "gormc database connections waited for.",
+		Labels:    []string{"db"},
+	})
+
+	metricDBWaitSeconds = metric.NewCounterVec(&metric.CounterVecOpts{
+		Namespace: dbNamespace,
+		Subsystem: "pool",
+		Name:      "wait_seconds_total",
+		Help:      "gormc time blocked waiting for database connections.",
+		Labels:    []string{"db"},
+	})
+
+	metricDBClosed = metric.NewCounterVec(&metric.CounterVecOpts{
+		Namespace: dbNamespace,
+		Subsystem: "pool",
+		Name:      "closed_total",
+		Help:      "gormc database connections closed by the pool limits.",
+		Labels:    []string{"db", "reason"},
+	})
+)
+
+func init() {
+	prometheus.MustRegister(metricHotKeyReads)
+}
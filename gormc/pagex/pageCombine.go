@@ -2,8 +2,10 @@ package pagex
 
 import (
 	"context"
+	"strings"
 
 	"github.com/huof6829/gorm-zero/gormc"
+	"github.com/zeromicro/go-zero/core/hash"
 	"gorm.io/gorm"
 )
 
@@ -24,11 +26,24 @@ func SetTableSortDesc(key string) {
 	tableSortDesc = key
 }
 
+// ListCachePrefix is the prefix of the cache keys of FindPageListCached.
+var ListCachePrefix = "cache:list:"
+
 type GormcCacheConn interface {
 	QueryNoCacheCtx(ctx context.Context, fn gormc.QueryCtxFn) error
 	ExecNoCacheCtx(ctx context.Context, execCtx gormc.ExecCtxFn) error
 }
 
+type GormcVersionedConn interface {
+	GormcCacheConn
+	QueryVersionedCtx(ctx context.Context, v interface{}, key string, tables []string, query gormc.QueryCtxFn) error
+}
+
+type cachedPage[T any] struct {
+	List  []T
+	Total int64
+}
+
 // FindPageList
 // fn first return db, second return countDb, if count sql need special handler (example: distinct on column), you can return countDb
 // if countDb is nil, default count is first db
@@ -88,6 +103,56 @@ func FindPageListMultiOrderBy[T any](ctx context.Context, cc GormcCacheConn, pag
 	return res, count, nil
 }
 
+// FindPageListCached is FindPageListMultiOrderBy with the list and the count cached by the versions of tables,
+// they are invalidated when any of tables is written through ExecCtx, see gormc.WithTableVersions.
+// The cache key is derived from the generated sql, so different conditions and pages are cached separately.
+func FindPageListCached[T any](ctx context.Context, cc GormcVersionedConn, tables []string, page *ListReq,
+	orderBys []OrderBy, orderKeys map[string]string, fn func(conn *gorm.DB) (*gorm.DB, *gorm.DB)) ([]T, int64, error) {
+	var key string
+	err := cc.QueryNoCacheCtx(ctx, func(conn *gorm.DB) error {
+		countSql := conn.ToSQL(func(tx *gorm.DB) *gorm.DB {
+			db, countDb := fn(tx)
+			if countDb != nil {
+				db = countDb
+			}
+			var count int64
+			return db.Count(&count)
+		})
+		listSql := conn.ToSQL(func(tx *gorm.DB) *gorm.DB {
+			db, _ := fn(tx)
+			db = db.Scopes(Paginate(page))
+			db = ApplyOrderBys(db, orderBys, orderKeys)
+			var res []T
+			return db.Find(&res)
+		})
+		key = ListCachePrefix + strings.Join(tables, ",") + ":" + hash.Md5Hex([]byte(countSql+";"+listSql))
+		return nil
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var res cachedPage[T]
+	err = cc.QueryVersionedCtx(ctx, &res, key, tables, func(conn *gorm.DB) error {
+		db, countDb := fn(conn)
+		if countDb != nil {
+			db = countDb
+		}
+		if err := db.Count(&res.Total).Error; err != nil {
+			return err
+		}
+
+		db, _ = fn(conn)
+		db = db.Scopes(Paginate(page))
+		db = ApplyOrderBys(db, orderBys, orderKeys)
+		return db.Find(&res.List).Error
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	return res.List, res.Total, nil
+}
+
 func ApplyOrderBys(db *gorm.DB, orderBys []OrderBy, orderKeys map[string]string) *gorm.DB {
 	for _, orderBy := range orderBys {
 		if orderStr, ok := orderKeys[orderBy.OrderKey]; ok {
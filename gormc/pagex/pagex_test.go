@@ -4,7 +4,9 @@ import (
 	"context"
 	"fmt"
 	"testing"
+	"time"
 
+	"github.com/alicebob/miniredis/v2"
 	"github.com/huof6829/gorm-zero/gormc"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
@@ -271,3 +273,64 @@ func TestFindPageListWithCountMultiOrderByWithMock(t *testing.T) {
 
 	fmt.Printf("Users: %+v, Count: %d\n", users, cnt)
 }
+
+func TestFindPageListCached(t *testing.T) {
+	db, err := createMockDB()
+	if err != nil {
+		t.Fatalf("Failed to create mock DB: %v", err)
+	}
+	mr := miniredis.RunT(t)
+	cache, err := gormc.NewRedisCache(gormc.RedisConfig{Addr: mr.Addr()}, time.Minute)
+	if err != nil {
+		t.Fatalf("Failed to create redis cache: %v", err)
+	}
+	defer cache.Close()
+	cachedConn := gormc.NewConnWithCache(db, cache, gormc.WithModelName("user"), gormc.WithTableVersions())
+
+	queries := 0
+	findPage := func(age int) ([]TestUserModel, int64) {
+		users, cnt, err := FindPageListCached[TestUserModel](
+			context.Background(),
+			cachedConn,
+			[]string{"user"},
+			&ListReq{Page: 1, PageSize: 5},
+			[]OrderBy{{OrderKey: "age", Sort: "asc"}},
+			map[string]string{"age": "age"},
+			func(conn *gorm.DB) (*gorm.DB, *gorm.DB) {
+				queries++
+				return conn.Model(&TestUserModel{}).Where("age >= ?", age), nil
+			},
+		)
+		if err != nil {
+			t.Fatalf("FindPageListCached Err,%v", err.Error())
+		}
+		return users, cnt
+	}
+
+	users, cnt := findPage(25)
+	if cnt != 6 || len(users) != 5 {
+		t.Fatalf("Expected count 6 and 5 users, got %d and %d", cnt, len(users))
+	}
+
+	// 第二次命中缓存，只生成 sql 计算 key
+	queries = 0
+	if _, cnt = findPage(25); cnt != 6 || queries != 2 {
+		t.Errorf("Expected a cache hit, got count %d with %d calls", cnt, queries)
+	}
+
+	// 不同条件使用不同的 key
+	if _, cnt = findPage(30); cnt != 3 {
+		t.Errorf("Expected count 3, got %d", cnt)
+	}
+
+	// 写表后版本号变化，列表自动失效
+	err = cachedConn.ExecCtx(context.Background(), func(conn *gorm.DB) error {
+		return conn.Create(&TestUserModel{Id: 9, Age: 40, Name: "Ivy"}).Error
+	})
+	if err != nil {
+		t.Fatalf("ExecCtx failed: %v", err)
+	}
+	if _, cnt = findPage(25); cnt != 7 {
+		t.Errorf("Expected count 7 after the write, got %d", cnt)
+	}
+}
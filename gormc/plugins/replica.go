@@ -0,0 +1,208 @@
+package plugins
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/huof6829/gorm-zero/gormc"
+	"github.com/zeromicro/go-zero/core/logx"
+	"github.com/zeromicro/go-zero/core/threading"
+	"gorm.io/gorm"
+	"gorm.io/plugin/dbresolver"
+)
+
+const (
+	replicaCallbackName = "gorm-zero-replica"
+
+	// ReplicaPolicyRandom picks a random healthy replica for each read.
+	ReplicaPolicyRandom = "random"
+	// ReplicaPolicyRoundRobin picks the healthy replicas in turn.
+	ReplicaPolicyRoundRobin = "round-robin"
+)
+
+type (
+	// ReplicaConf configures the replicas of a ReplicaPlugin.
+	ReplicaConf struct {
+		Policy          string        // random or round-robin, defaults to random
+		CheckInterval   time.Duration // how often the replicas are pinged, 0 disables the health checks
+		MaxIdleConns    int
+		MaxOpenConns    int
+		ConnMaxLifetime time.Duration // 0 means no limit
+		ConnMaxIdleTime time.Duration // 0 means no limit
+	}
+
+	// ReplicaPlugin splits reads and writes with gorm dbresolver.
+	//
+	// Queries outside transactions read from the replicas, writes and transactions go to the primary.
+	// A context marked by gormc.WithPrimary reads from the primary. Replicas failing the health check
+	// are skipped until they recover, the primary serves the reads if all of them are down.
+	ReplicaPlugin struct {
+		replicas []gorm.Dialector
+		conf     ReplicaConf
+		base     dbresolver.Policy
+		primary  gorm.ConnPool
+		pools    []gorm.ConnPool
+		down     sync.Map // gorm.ConnPool -> struct{}
+		done     chan struct{}
+		once     sync.Once
+	}
+
+	pinger interface {
+		PingContext(ctx context.Context) error
+	}
+)
+
+// NewReplicaPlugin returns a ReplicaPlugin reading from replicas, register it with db.Use.
+func NewReplicaPlugin(replicas []gorm.Dialector, conf ReplicaConf) *ReplicaPlugin {
+	var base dbresolver.Policy = dbresolver.RandomPolicy{}
+	if conf.Policy == ReplicaPolicyRoundRobin {
+		base = dbresolver.StrictRoundRobinPolicy()
+	}
+	return &ReplicaPlugin{
+		replicas: replicas,
+		conf:     conf,
+		base:     base,
+		done:     make(chan struct{}),
+	}
+}
+
+func (p *ReplicaPlugin) Name() string {
+	return "gorm-zero-replica-plugin"
+}
+
+func (p *ReplicaPlugin) Initialize(db *gorm.DB) error {
+	p.primary = unwrapConnPool(db.Config.ConnPool)
+	resolver := dbresolver.Register(dbresolver.Config{
+		Replicas: p.replicas,
+		Policy:   p,
+	})
+	if err := db.Use(resolver); err != nil {
+		return err
+	}
+
+	// the pools of the resolver are the primary followed by the replicas
+	if err := resolver.Call(func(pool gorm.ConnPool) error {
+		if pool = unwrapConnPool(pool); pool != p.primary {
+			p.pools = append(p.pools, pool)
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+	// not with the resolver, which would apply them to the primary too
+	for _, pool := range p.pools {
+		if sqlDB, ok := pool.(*sql.DB); ok {
+			if p.conf.MaxIdleConns > 0 {
+				sqlDB.SetMaxIdleConns(p.conf.MaxIdleConns)
+			}
+			if p.conf.MaxOpenConns > 0 {
+				sqlDB.SetMaxOpenConns(p.conf.MaxOpenConns)
+			}
+			sqlDB.SetConnMaxLifetime(p.conf.ConnMaxLifetime)
+			sqlDB.SetConnMaxIdleTime(p.conf.ConnMaxIdleTime)
+		}
+	}
+
+	// after the resolver, which is registered before all the others
+	db.Callback().Query().Before("gorm:query").Register(replicaCallbackName, p.route)
+	db.Callback().Row().Before("gorm:row").Register(replicaCallbackName, p.route)
+	db.Callback().Raw().Before("gorm:raw").Register(replicaCallbackName, p.route)
+
+	if p.conf.CheckInterval > 0 {
+		threading.GoSafe(p.checkLoop)
+	}
+	return nil
+}
+
+// Resolve implements dbresolver.Policy, it picks one of the healthy pools, or the primary if none.
+func (p *ReplicaPlugin) Resolve(pools []gorm.ConnPool) gorm.ConnPool {
+	healthy := make([]gorm.ConnPool, 0, len(pools))
+	for _, pool := range pools {
+		if !p.isDown(pool) {
+			healthy = append(healthy, pool)
+		}
+	}
+	if len(healthy) == 0 {
+		return p.primary
+	}
+	return p.base.Resolve(healthy)
+}
+
+// route sends the reads of a context marked by gormc.WithPrimary to the primary,
+// and moves the reads off a replica that is down.
+func (p *ReplicaPlugin) route(db *gorm.DB) {
+	if gormc.IsPrimary(db.Statement.Context) {
+		dbresolver.Write.ModifyStatement(db.Statement)
+		return
+	}
+	// the resolver skips the policy when there is only one replica
+	if p.isDown(db.Statement.ConnPool) {
+		db.Statement.ConnPool = p.Resolve(p.pools)
+	}
+}
+
+func (p *ReplicaPlugin) isDown(pool gorm.ConnPool) bool {
+	_, ok := p.down.Load(unwrapConnPool(pool))
+	return ok
+}
+
+func (p *ReplicaPlugin) checkLoop() {
+	ticker := time.NewTicker(p.conf.CheckInterval)
+	defer ticker.Stop()
+
+	p.check()
+	for {
+		select {
+		case <-ticker.C:
+			p.check()
+		case <-p.done:
+			return
+		}
+	}
+}
+
+func (p *ReplicaPlugin) check() {
+	for i, pool := range p.pools {
+		pg, ok := pool.(pinger)
+		if !ok {
+			continue
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), p.conf.CheckInterval)
+		err := pg.PingContext(ctx)
+		cancel()
+		if err != nil {
+			if _, loaded := p.down.LoadOrStore(pool, struct{}{}); !loaded {
+				logx.Errorf("gorm-zero: replica %d is down: %v", i, err)
+			}
+		} else if _, loaded := p.down.LoadAndDelete(pool); loaded {
+			logx.Infof("gorm-zero: replica %d is up", i)
+		}
+	}
+}
+
+// Close stops the health checks and closes the replicas, the primary is closed with the *gorm.DB.
+func (p *ReplicaPlugin) Close() error {
+	var err error
+	p.once.Do(func() {
+		close(p.done)
+		for _, pool := range p.pools {
+			if sqlDB, ok := pool.(*sql.DB); ok {
+				err = errors.Join(err, sqlDB.Close())
+			}
+		}
+	})
+	return err
+}
+
+func unwrapConnPool(pool gorm.ConnPool) gorm.ConnPool {
+	if prepared, ok := pool.(*gorm.PreparedStmtDB); ok {
+		return prepared.ConnPool
+	}
+	return pool
+}
+
+var _ gorm.Plugin = &ReplicaPlugin{}
@@ -0,0 +1,23 @@
+package gormc
+
+import "context"
+
+type primaryCtxKey struct{}
+
+// WithPrimary marks ctx to read from the primary database when replicas are configured,
+// e.g. to read a row right after writing it.
+//
+// The queries filling the cache by key always read from the primary, a lagging replica would cache stale rows
+// until they expire. QueryNoCacheCtx, QueryVersionedCtx and the page queries read from the replicas.
+func WithPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryCtxKey{}, true)
+}
+
+// IsPrimary reports whether ctx is marked to read from the primary database.
+func IsPrimary(ctx context.Context) bool {
+	if ctx == nil {
+		return false
+	}
+	primary, _ := ctx.Value(primaryCtxKey{}).(bool)
+	return primary
+}
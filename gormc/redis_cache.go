@@ -5,33 +5,173 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/redis/go-redis/v9"
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+const (
+	// BigValueSkip doesn't cache values exceeding MaxValueSize and returns no error.
+	BigValueSkip = "skip"
+	// BigValueReject doesn't cache values exceeding MaxValueSize and returns a *ValueTooLargeError.
+	BigValueReject = "reject"
 )
 
 var (
 	// ErrCacheMiss indicates the key is not found in cache.
 	ErrCacheMiss = errors.New("cache: key not found")
+	// ErrValueTooLarge indicates the value exceeds the configured max value size.
+	ErrValueTooLarge = errors.New("cache: value too large")
 )
 
+// ValueTooLargeError is returned by SetWithExpireCtx with the BigValueReject policy.
+type ValueTooLargeError struct {
+	Key   string
+	Size  int
+	Limit int
+}
+
+func (e *ValueTooLargeError) Error() string {
+	return fmt.Sprintf("cache: value of key %q is %d bytes, exceeds limit %d bytes", e.Key, e.Size, e.Limit)
+}
+
+// Unwrap makes errors.Is(err, ErrValueTooLarge) work.
+func (e *ValueTooLargeError) Unwrap() error {
+	return ErrValueTooLarge
+}
+
 // RedisConfig is the redis configuration.
+// It can be loaded with go-zero conf.MustLoad, exactly one of Addr, ClusterAddrs and SentinelAddrs must be set.
 type RedisConfig struct {
 	// 单节点模式配置
-	Addr     string // Redis server address (single node)
-	Username string // Redis username (optional, for ACL authentication)
-	Password string // Redis password
-	DB       int    // Redis database index (only for single node, cluster doesn't support DB)
+	Addr     string `json:",optional"`  // Redis server address (single node)
+	Username string `json:",optional"`  // Redis username (optional, for ACL authentication)
+	Password string `json:",optional"`  // Redis password
+	DB       int    `json:",default=0"` // Redis database index (cluster doesn't support DB)
+	// Credentials reads the username and password on each new connection instead of Username and Password,
+	// so that rotated secrets are picked up without restart. Sentinel mode reads them once on startup.
+	Credentials CredentialConf `json:",optional"`
 
 	// Cluster 模式配置
-	ClusterAddrs []string // Redis cluster addresses (e.g., []string{"localhost:7000", "localhost:7001"})
+	ClusterAddrs []string `json:",optional"` // Redis cluster addresses (e.g., []string{"localhost:7000", "localhost:7001"})
+	// ReadOnly routes read-only commands (GET) to replica nodes, write commands (SET/DEL) always go to masters.
+	// Replication is asynchronous, so a read right after a write or a delete may still see the old value
+	// for a short period. Only enable it when a slightly stale cache hit is acceptable.
+	ReadOnly bool `json:",optional"`
+	// RouteByLatency routes read-only commands to the closest master or replica node. It implies ReadOnly.
+	RouteByLatency bool `json:",optional"`
+	// RouteRandomly routes read-only commands to a random master or replica node. It implies ReadOnly.
+	RouteRandomly bool `json:",optional"`
+
+	// Sentinel 模式配置
+	MasterName       string   `json:",optional"` // Sentinel master name
+	SentinelAddrs    []string `json:",optional"` // Sentinel addresses
+	SentinelUsername string   `json:",optional"` // Sentinel username (optional)
+	SentinelPassword string   `json:",optional"` // Sentinel password (optional)
 
 	// 通用配置
-	PoolSize     int           // Connection pool size
-	MinIdleConns int           // Minimum idle connections
-	DialTimeout  time.Duration // Dial timeout
-	ReadTimeout  time.Duration // Read timeout
-	WriteTimeout time.Duration // Write timeout
+	PoolSize     int           `json:",default=10"` // Connection pool size
+	MinIdleConns int           `json:",default=2"`  // Minimum idle connections
+	DialTimeout  time.Duration `json:",default=5s"` // Dial timeout
+	ReadTimeout  time.Duration `json:",default=3s"` // Read timeout
+	WriteTimeout time.Duration `json:",default=3s"` // Write timeout
+	Retry        RetryConf     `json:",optional"`   // Retry of the connection on startup
+
+	HotKey HotKeyConf `json:",optional"` // Hot key detection and local caching
+
+	// 大 value 保护
+	MaxValueSize   int    `json:",optional"`                         // Max encoded value size in bytes, 0 means unlimited
+	BigValuePolicy string `json:",default=skip,options=skip|reject"` // What to do with values exceeding MaxValueSize
+
+	Policies *CachePolicyConf `json:",optional"` // Per model or key prefix cache policies
+}
+
+// IsCluster reports whether the config describes a redis cluster.
+func (c RedisConfig) IsCluster() bool {
+	return len(c.ClusterAddrs) > 0
+}
+
+// IsSentinel reports whether the config describes a sentinel managed redis.
+func (c RedisConfig) IsSentinel() bool {
+	return len(c.SentinelAddrs) > 0
+}
+
+// Validate checks the config and returns a descriptive error if it is invalid.
+// It is called by NewRedisCache before connecting, and by go-zero conf.Load only when RedisConfig is
+// the loaded struct itself, go-zero doesn't validate nested configs.
+func (c RedisConfig) Validate() error {
+	modes := 0
+	for _, set := range []bool{c.Addr != "", c.IsCluster(), c.IsSentinel()} {
+		if set {
+			modes++
+		}
+	}
+	switch {
+	case modes == 0:
+		return errors.New("redis config error: one of Addr, ClusterAddrs or SentinelAddrs must be set")
+	case modes > 1:
+		return errors.New("redis config error: Addr, ClusterAddrs and SentinelAddrs are mutually exclusive")
+	}
+
+	if c.DB < 0 {
+		return fmt.Errorf("redis config error: DB must not be negative, got %d", c.DB)
+	}
+	if c.IsCluster() && c.DB != 0 {
+		return fmt.Errorf("redis config error: DB must be 0 in cluster mode, got %d", c.DB)
+	}
+	if !c.IsCluster() && (c.ReadOnly || c.RouteByLatency || c.RouteRandomly) {
+		return errors.New("redis config error: ReadOnly, RouteByLatency and RouteRandomly are only supported in cluster mode")
+	}
+	if c.IsSentinel() && c.MasterName == "" {
+		return errors.New("redis config error: MasterName is required in sentinel mode")
+	}
+	if !c.IsSentinel() && c.MasterName != "" {
+		return errors.New("redis config error: MasterName is set but SentinelAddrs is empty")
+	}
+
+	if c.PoolSize < 0 {
+		return fmt.Errorf("redis config error: PoolSize must not be negative, got %d", c.PoolSize)
+	}
+	if c.MinIdleConns < 0 {
+		return fmt.Errorf("redis config error: MinIdleConns must not be negative, got %d", c.MinIdleConns)
+	}
+	if c.PoolSize > 0 && c.MinIdleConns > c.PoolSize {
+		return fmt.Errorf("redis config error: MinIdleConns (%d) must not exceed PoolSize (%d)", c.MinIdleConns, c.PoolSize)
+	}
+	if c.DialTimeout < 0 || c.ReadTimeout < 0 || c.WriteTimeout < 0 {
+		return errors.New("redis config error: DialTimeout, ReadTimeout and WriteTimeout must not be negative")
+	}
+	if c.MaxValueSize < 0 {
+		return fmt.Errorf("redis config error: MaxValueSize must not be negative, got %d", c.MaxValueSize)
+	}
+	if c.BigValuePolicy != "" && c.BigValuePolicy != BigValueSkip && c.BigValuePolicy != BigValueReject {
+		return fmt.Errorf("redis config error: unknown BigValuePolicy %q", c.BigValuePolicy)
+	}
+
+	return nil
+}
+
+// withDefaults fills the zero values of a config that was not loaded by go-zero conf.
+func (c RedisConfig) withDefaults() RedisConfig {
+	if c.DialTimeout == 0 {
+		c.DialTimeout = 5 * time.Second
+	}
+	if c.ReadTimeout == 0 {
+		c.ReadTimeout = 3 * time.Second
+	}
+	if c.WriteTimeout == 0 {
+		c.WriteTimeout = 3 * time.Second
+	}
+	if c.PoolSize == 0 {
+		c.PoolSize = 10
+	}
+	if c.MinIdleConns == 0 {
+		c.MinIdleConns = 2
+	}
+	return c
 }
 
 // RedisCache is a cache implementation based on native go-redis.
@@ -40,77 +180,180 @@ type RedisCache struct {
 	client        redis.Cmdable // Universal client interface (supports both Client and ClusterClient)
 	notFoundError error
 	expiry        time.Duration
+	hotKeys       *hotKeyDetector
+	maxValueSize  int
+	rejectBig     bool
+	policies      *CachePolicies
+	mode          *atomic.Int32
+	migration     *cacheMigration
+	skipped       *skippedInvalidations // invalidations skipped while started degraded
+
+	// async work in flight, drained by Close
+	asyncMu   sync.Mutex
+	async     sync.WaitGroup
+	closed    bool
+	done      chan struct{} // closed by Close, stops the background loops
+	closeOnce sync.Once
+	closeErr  error
 }
 
+// RedisCacheOption customizes a RedisCache.
+type RedisCacheOption func(c *RedisCache)
+
 // NewRedisCache creates a new RedisCache instance.
-// Supports both single node and cluster mode:
+// Supports single node, cluster and sentinel mode:
 // - Single node: set Addr field
-// - Cluster: set ClusterAddrs field (Addr will be ignored)
-func NewRedisCache(conf RedisConfig, expiry time.Duration) (*RedisCache, error) {
-	// Set default values
-	if conf.DialTimeout == 0 {
-		conf.DialTimeout = 5 * time.Second
+// - Cluster: set ClusterAddrs field
+// - Sentinel: set SentinelAddrs and MasterName fields
+func NewRedisCache(conf RedisConfig, expiry time.Duration, opts ...RedisCacheOption) (*RedisCache, error) {
+	conf = conf.withDefaults()
+	client, err := newRedisClient(conf)
+	if err != nil {
+		return nil, err
 	}
-	if conf.ReadTimeout == 0 {
-		conf.ReadTimeout = 3 * time.Second
+
+	var confOpts []RedisCacheOption
+	if err = pingRedis(client, conf); err != nil {
+		if !conf.Retry.Degraded {
+			_ = closeClient(client)
+			return nil, err
+		}
+		confOpts = append(confOpts, withDegraded(client, conf, err))
 	}
-	if conf.WriteTimeout == 0 {
-		conf.WriteTimeout = 3 * time.Second
+	if conf.HotKey.Enabled {
+		confOpts = append(confOpts, WithHotKeyDetection(conf.HotKey))
 	}
-	if conf.PoolSize == 0 {
-		conf.PoolSize = 10
+	if conf.MaxValueSize > 0 {
+		confOpts = append(confOpts, WithMaxValueSize(conf.MaxValueSize, conf.BigValuePolicy))
 	}
-	if conf.MinIdleConns == 0 {
-		conf.MinIdleConns = 2
+	if conf.Policies != nil {
+		confOpts = append(confOpts, WithCachePolicies(NewCachePolicies(*conf.Policies)))
 	}
+	opts = append(confOpts, opts...)
 
-	var client redis.Cmdable
+	return NewRedisCacheWithClient(client, expiry, opts...), nil
+}
 
-	// Determine mode: Cluster or Single Node
-	if len(conf.ClusterAddrs) > 0 {
+// NewRedisClient creates a redis client of the mode of conf and checks the connection,
+// e.g. to create the secondary client of WithSecondary.
+// With conf.Retry.Degraded, the client is returned even if redis is unreachable.
+func NewRedisClient(conf RedisConfig) (redis.Cmdable, error) {
+	conf = conf.withDefaults()
+	client, err := newRedisClient(conf)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = pingRedis(client, conf); err != nil {
+		if !conf.Retry.Degraded {
+			_ = closeClient(client)
+			return nil, err
+		}
+		logx.Errorf("gormc: starting with redis unreachable: %v", err)
+	}
+	return client, nil
+}
+
+func newRedisClient(conf RedisConfig) (redis.Cmdable, error) {
+	if err := conf.Validate(); err != nil {
+		return nil, err
+	}
+
+	provider, err := conf.Credentials.NewProvider()
+	if err != nil {
+		return nil, err
+	}
+	var credentials func(ctx context.Context) (string, string, error)
+	if provider != nil {
+		credentials = func(ctx context.Context) (string, string, error) {
+			creds, err := ResolveCredentials(ctx, provider, conf.Username)
+			return creds.Username, creds.Password, err
+		}
+	}
+
+	// Determine mode: Cluster, Sentinel or Single Node
+	switch {
+	case conf.IsCluster():
 		// Redis Cluster Mode
-		clusterClient := redis.NewClusterClient(&redis.ClusterOptions{
-			Addrs:        conf.ClusterAddrs,
-			Username:     conf.Username,
-			Password:     conf.Password,
-			PoolSize:     conf.PoolSize,
-			MinIdleConns: conf.MinIdleConns,
-			DialTimeout:  conf.DialTimeout,
-			ReadTimeout:  conf.ReadTimeout,
-			WriteTimeout: conf.WriteTimeout,
-		})
-		client = clusterClient
-	} else {
-		// Single Node Mode
-		if conf.Addr == "" {
-			return nil, fmt.Errorf("redis config error: either Addr or ClusterAddrs must be set")
+		options := newClusterOptions(conf)
+		options.CredentialsProviderContext = credentials
+		return redis.NewClusterClient(options), nil
+	case conf.IsSentinel():
+		// Redis Sentinel Mode, the failover options have no credentials provider
+		username, password := conf.Username, conf.Password
+		if credentials != nil {
+			if username, password, err = credentials(context.Background()); err != nil {
+				return nil, err
+			}
 		}
-		singleClient := redis.NewClient(&redis.Options{
-			Addr:         conf.Addr,
-			Username:     conf.Username,
-			Password:     conf.Password,
-			DB:           conf.DB,
-			PoolSize:     conf.PoolSize,
-			MinIdleConns: conf.MinIdleConns,
-			DialTimeout:  conf.DialTimeout,
-			ReadTimeout:  conf.ReadTimeout,
-			WriteTimeout: conf.WriteTimeout,
-		})
-		client = singleClient
-	}
-
-	// Test connection
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-	if err := client.Ping(ctx).Err(); err != nil {
-		return nil, fmt.Errorf("failed to connect to redis: %w", err)
+		return redis.NewFailoverClient(&redis.FailoverOptions{
+			MasterName:       conf.MasterName,
+			SentinelAddrs:    conf.SentinelAddrs,
+			SentinelUsername: conf.SentinelUsername,
+			SentinelPassword: conf.SentinelPassword,
+			Username:         username,
+			Password:         password,
+			DB:               conf.DB,
+			PoolSize:         conf.PoolSize,
+			MinIdleConns:     conf.MinIdleConns,
+			DialTimeout:      conf.DialTimeout,
+			ReadTimeout:      conf.ReadTimeout,
+			WriteTimeout:     conf.WriteTimeout,
+		}), nil
+	default:
+		// Single Node Mode
+		return redis.NewClient(&redis.Options{
+			Addr:                       conf.Addr,
+			Username:                   conf.Username,
+			Password:                   conf.Password,
+			CredentialsProviderContext: credentials,
+			DB:                         conf.DB,
+			PoolSize:                   conf.PoolSize,
+			MinIdleConns:               conf.MinIdleConns,
+			DialTimeout:                conf.DialTimeout,
+			ReadTimeout:                conf.ReadTimeout,
+			WriteTimeout:               conf.WriteTimeout,
+		}), nil
 	}
+}
 
-	return &RedisCache{
-		client:        client,
-		notFoundError: ErrNotFound,
-		expiry:        expiry,
-	}, nil
+// pingRedis tests the connection of client, retrying as conf.Retry says.
+func pingRedis(client redis.Cmdable, conf RedisConfig) error {
+	err := RetryCtx(context.Background(), "redis", conf.Retry, func(ctx context.Context) error {
+		ctx, cancel := context.WithTimeout(ctx, conf.DialTimeout)
+		defer cancel()
+		return client.Ping(ctx).Err()
+	})
+	if err != nil {
+		return fmt.Errorf("failed to connect to redis: %w", err)
+	}
+	return nil
+}
+
+// newClusterOptions builds the cluster client options from conf.
+func newClusterOptions(conf RedisConfig) *redis.ClusterOptions {
+	return &redis.ClusterOptions{
+		Addrs:          conf.ClusterAddrs,
+		Username:       conf.Username,
+		Password:       conf.Password,
+		PoolSize:       conf.PoolSize,
+		MinIdleConns:   conf.MinIdleConns,
+		DialTimeout:    conf.DialTimeout,
+		ReadTimeout:    conf.ReadTimeout,
+		WriteTimeout:   conf.WriteTimeout,
+		ReadOnly:       conf.ReadOnly || conf.RouteByLatency || conf.RouteRandomly,
+		RouteByLatency: conf.RouteByLatency,
+		RouteRandomly:  conf.RouteRandomly,
+	}
+}
+
+// WithMaxValueSize limits the encoded size of cached values, policy is BigValueSkip or BigValueReject.
+// Values exceeding the limit are not cached, they are logged with key and size and counted in metrics.
+func WithMaxValueSize(limit int, policy string) RedisCacheOption {
+	return func(c *RedisCache) {
+		c.maxValueSize = limit
+		c.rejectBig = policy == BigValueReject
+	}
 }
 
 // DelCtx deletes cached values with keys.
@@ -118,51 +361,163 @@ func (c *RedisCache) DelCtx(ctx context.Context, keys ...string) error {
 	if len(keys) == 0 {
 		return nil
 	}
-	return c.client.Del(ctx, keys...).Err()
+	if !c.Mode().deletable() && c.skipped.record(keys, nil) {
+		return nil
+	}
+	c.evictLocal(keys...)
+	return c.del(ctx, keys...)
 }
 
 // GetCtx unmarshals cache with given key into v.
 func (c *RedisCache) GetCtx(ctx context.Context, key string, v interface{}) error {
-	data, err := c.client.Get(ctx, key).Bytes()
+	return c.getCtx(ctx, key, v, c.lookupPolicy("", key))
+}
+
+func (c *RedisCache) getCtx(ctx context.Context, key string, v interface{}, policy CachePolicy) error {
+	if policy.Disabled || !c.Mode().readable() {
+		return ErrCacheMiss
+	}
+
+	data, err := c.getBytes(ctx, key, policy)
+	if err != nil {
+		return err
+	}
+	if string(data) == notFoundPlaceholder {
+		return c.notFoundError
+	}
+
+	return json.Unmarshal(data, v)
+}
+
+// getBytes returns the decoded value of key, the local caches are looked up first.
+func (c *RedisCache) getBytes(ctx context.Context, key string, policy CachePolicy) ([]byte, error) {
+	var hot bool
+	if c.hotKeys != nil {
+		hot = c.hotKeys.record(key)
+		if data, ok := c.hotKeys.getLocal(key); ok {
+			metricHotKeys.Inc("local_hit")
+			return data, nil
+		}
+	}
+	if policy.LocalCache {
+		if data, ok := c.policies.getLocal(key); ok {
+			return data, nil
+		}
+	}
+
+	data, err := c.get(ctx, key)
 	if err != nil {
 		if errors.Is(err, redis.Nil) {
-			return ErrCacheMiss
+			return nil, ErrCacheMiss
 		}
-		return err
+		return nil, err
 	}
 
 	if len(data) == 0 {
-		return ErrCacheMiss
+		return nil, ErrCacheMiss
+	}
+	if data, err = decompress(data); err != nil {
+		return nil, err
+	}
+	if hot {
+		c.hotKeys.promote(key, data)
+	}
+	if policy.LocalCache {
+		c.policies.setLocal(key, data, policy.LocalExpiry)
 	}
 
-	return json.Unmarshal(data, v)
+	return data, nil
 }
 
 // SetCtx sets cache with given key and value.
 func (c *RedisCache) SetCtx(ctx context.Context, key string, v interface{}) error {
-	return c.SetWithExpireCtx(ctx, key, v, c.expiry)
+	policy := c.lookupPolicy("", key)
+	return c.setCtx(ctx, key, v, c.expiryOf(policy), policy)
 }
 
 // SetWithExpireCtx sets cache with given key, value and expire time.
 func (c *RedisCache) SetWithExpireCtx(ctx context.Context, key string, v interface{}, expire time.Duration) error {
+	return c.setCtx(ctx, key, v, expire, c.lookupPolicy("", key))
+}
+
+func (c *RedisCache) setCtx(ctx context.Context, key string, v interface{}, expire time.Duration, policy CachePolicy) error {
 	data, err := json.Marshal(v)
 	if err != nil {
 		return fmt.Errorf("failed to marshal value: %w", err)
 	}
 
-	return c.client.Set(ctx, key, data, expire).Err()
+	return c.setBytes(ctx, key, data, expire, policy)
+}
+
+func (c *RedisCache) setBytes(ctx context.Context, key string, data []byte, expire time.Duration, policy CachePolicy) error {
+	return c.writeBytes(ctx, key, data, expire, policy, c.set)
+}
+
+// writeBytes applies the mode and policy of the cache to a write of key, then writes data with write.
+func (c *RedisCache) writeBytes(ctx context.Context, key string, data []byte, expire time.Duration, policy CachePolicy,
+	write func(ctx context.Context, key string, data []byte, expire time.Duration) error) (err error) {
+	mode := c.Mode()
+	if !mode.deletable() && c.skipped.record([]string{key}, nil) {
+		return nil
+	}
+	c.evictLocal(key)
+	if policy.Disabled || !mode.writable() {
+		// keep invalidating, so that the cache is consistent when it is enabled again
+		return c.del(ctx, key)
+	}
+
+	if policy.Compress {
+		if data, err = compress(data); err != nil {
+			return fmt.Errorf("failed to compress value: %w", err)
+		}
+	}
+	if c.maxValueSize > 0 && len(data) > c.maxValueSize {
+		return c.handleBigValue(ctx, key, len(data))
+	}
+
+	return write(ctx, key, data, expire)
+}
+
+func (c *RedisCache) handleBigValue(ctx context.Context, key string, size int) error {
+	policy := BigValueSkip
+	if c.rejectBig {
+		policy = BigValueReject
+	}
+	metricBigValues.Inc(policy)
+	logx.WithContext(ctx).Errorf("gormc: value of key %q is %d bytes, exceeds limit %d bytes, not cached",
+		key, size, c.maxValueSize)
+
+	// drop the previous value, otherwise it would be served as if the write had succeeded
+	if err := c.del(ctx, key); err != nil {
+		return err
+	}
+	if c.rejectBig {
+		return &ValueTooLargeError{Key: key, Size: size, Limit: c.maxValueSize}
+	}
+	return nil
 }
 
 // TakeCtx takes the result from cache first, if not found,
 // query from the query function and set cache with the result.
 func (c *RedisCache) TakeCtx(ctx context.Context, v interface{}, key string, query func(v interface{}) error) error {
-	return c.TakeWithExpireCtx(ctx, v, key, query, c.expiry)
+	policy := c.lookupPolicy("", key)
+	return c.takeCtx(ctx, v, key, query, c.expiryOf(policy), policy)
 }
 
 // TakeWithExpireCtx takes the result from cache first, if not found,
 // query from the query function and set cache with the result with given expire time.
 func (c *RedisCache) TakeWithExpireCtx(ctx context.Context, v interface{}, key string, query func(v interface{}) error, expire time.Duration) error {
-	err := c.GetCtx(ctx, key, v)
+	return c.takeCtx(ctx, v, key, query, expire, c.lookupPolicy("", key))
+}
+
+func (c *RedisCache) takeCtx(ctx context.Context, v interface{}, key string, query func(v interface{}) error,
+	expire time.Duration, policy CachePolicy) error {
+	mode := c.Mode()
+	if policy.Disabled || !(mode.readable() || mode.writable()) {
+		return query(v)
+	}
+
+	err := c.getCtx(ctx, key, v, policy)
 	if err == nil {
 		return nil
 	}
@@ -173,11 +528,14 @@ func (c *RedisCache) TakeWithExpireCtx(ctx context.Context, v interface{}, key s
 
 	// Query from database
 	if err := query(v); err != nil {
+		if policy.NotFoundExpiry > 0 && errors.Is(err, c.notFoundError) {
+			_ = c.setBytes(ctx, key, []byte(notFoundPlaceholder), policy.NotFoundExpiry, policy)
+		}
 		return err
 	}
 
 	// Set cache with the result
-	if err := c.SetWithExpireCtx(ctx, key, v, expire); err != nil {
+	if err := c.setCtx(ctx, key, v, expire, policy); err != nil {
 		// Log error but don't fail the request
 		// You might want to add proper logging here
 		_ = err
@@ -186,10 +544,51 @@ func (c *RedisCache) TakeWithExpireCtx(ctx context.Context, v interface{}, key s
 	return nil
 }
 
-// Close closes the redis client.
+// Policies returns the cache policies of c, nil if no policies are configured.
+func (c *RedisCache) Policies() *CachePolicies {
+	return c.policies
+}
+
+// lookupPolicy returns the policy of model or key, the zero policy means the defaults.
+func (c *RedisCache) lookupPolicy(model, key string) CachePolicy {
+	policy, _ := c.policies.Lookup(model, key)
+	return policy
+}
+
+// expiryOf returns the expiry of policy, falls back to the expiry of c.
+func (c *RedisCache) expiryOf(policy CachePolicy) time.Duration {
+	if policy.Expiry > 0 {
+		return policy.Expiry
+	}
+	return c.expiry
+}
+
+func (c *RedisCache) evictLocal(keys ...string) {
+	if c.hotKeys != nil {
+		c.hotKeys.evict(keys...)
+	}
+	c.policies.evict(keys...)
+}
+
+// Close waits for the async work in flight, then closes the redis client,
+// and the secondary client while migrating.
 func (c *RedisCache) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), drainTimeout)
+	defer cancel()
+	return c.CloseCtx(ctx)
+}
+
+func (c *RedisCache) closeClients() error {
+	err := closeClient(c.primary())
+	if secondary := c.secondary(); secondary != nil {
+		err = errors.Join(err, closeClient(secondary))
+	}
+	return err
+}
+
+func closeClient(client redis.Cmdable) error {
 	// Type assert to get the Close method
-	switch client := c.client.(type) {
+	switch client := client.(type) {
 	case *redis.Client:
 		return client.Close()
 	case *redis.ClusterClient:
@@ -199,19 +598,26 @@ func (c *RedisCache) Close() error {
 	}
 }
 
-// GetClient returns the underlying redis client.
+// GetClient returns the underlying redis client, the primary one while migrating.
 // Returns redis.Cmdable interface which can be either *redis.Client or *redis.ClusterClient
 func (c *RedisCache) GetClient() redis.Cmdable {
-	return c.client
+	return c.primary()
 }
 
 // NewRedisCacheWithClient creates a RedisCache from an existing redis client.
 // This is useful when you want to reuse an existing redis connection instead of creating a new one.
 // The client can be either *redis.Client or *redis.ClusterClient.
-func NewRedisCacheWithClient(client redis.Cmdable, expiry time.Duration) *RedisCache {
-	return &RedisCache{
+func NewRedisCacheWithClient(client redis.Cmdable, expiry time.Duration, opts ...RedisCacheOption) *RedisCache {
+	c := &RedisCache{
 		client:        client,
 		notFoundError: ErrNotFound,
 		expiry:        expiry,
+		mode:          new(atomic.Int32),
+		done:          make(chan struct{}),
+	}
+	metricCacheMode.Inc(c.Mode().String())
+	for _, opt := range opts {
+		opt(c)
 	}
+	return c
 }
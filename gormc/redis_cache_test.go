@@ -0,0 +1,94 @@
+package gormc
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zeromicro/go-zero/core/conf"
+)
+
+func TestNewClusterOptions_ReplicaReads(t *testing.T) {
+	tests := []struct {
+		name           string
+		conf           RedisConfig
+		readOnly       bool
+		routeByLatency bool
+		routeRandomly  bool
+	}{
+		{name: "masters only", conf: RedisConfig{}},
+		{name: "read only", conf: RedisConfig{ReadOnly: true}, readOnly: true},
+		{name: "route by latency", conf: RedisConfig{RouteByLatency: true}, readOnly: true, routeByLatency: true},
+		{name: "route randomly", conf: RedisConfig{RouteRandomly: true}, readOnly: true, routeRandomly: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.conf.ClusterAddrs = []string{"127.0.0.1:7000"}
+			opts := newClusterOptions(tt.conf)
+			if opts.ReadOnly != tt.readOnly {
+				t.Errorf("ReadOnly = %v, want %v", opts.ReadOnly, tt.readOnly)
+			}
+			if opts.RouteByLatency != tt.routeByLatency {
+				t.Errorf("RouteByLatency = %v, want %v", opts.RouteByLatency, tt.routeByLatency)
+			}
+			if opts.RouteRandomly != tt.routeRandomly {
+				t.Errorf("RouteRandomly = %v, want %v", opts.RouteRandomly, tt.routeRandomly)
+			}
+		})
+	}
+}
+
+func TestRedisConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		conf    RedisConfig
+		wantErr string
+	}{
+		{name: "single node", conf: RedisConfig{Addr: "127.0.0.1:6379", DB: 1}},
+		{name: "cluster", conf: RedisConfig{ClusterAddrs: []string{"127.0.0.1:7000"}, ReadOnly: true}},
+		{name: "sentinel", conf: RedisConfig{SentinelAddrs: []string{"127.0.0.1:26379"}, MasterName: "mymaster"}},
+		{name: "no address", conf: RedisConfig{}, wantErr: "must be set"},
+		{name: "addr and cluster", conf: RedisConfig{Addr: "127.0.0.1:6379", ClusterAddrs: []string{"127.0.0.1:7000"}}, wantErr: "mutually exclusive"},
+		{name: "cluster with db", conf: RedisConfig{ClusterAddrs: []string{"127.0.0.1:7000"}, DB: 1}, wantErr: "DB must be 0 in cluster mode"},
+		{name: "read only on single node", conf: RedisConfig{Addr: "127.0.0.1:6379", ReadOnly: true}, wantErr: "only supported in cluster mode"},
+		{name: "sentinel without master", conf: RedisConfig{SentinelAddrs: []string{"127.0.0.1:26379"}}, wantErr: "MasterName is required"},
+		{name: "master without sentinel", conf: RedisConfig{Addr: "127.0.0.1:6379", MasterName: "mymaster"}, wantErr: "SentinelAddrs is empty"},
+		{name: "idle exceeds pool", conf: RedisConfig{Addr: "127.0.0.1:6379", PoolSize: 2, MinIdleConns: 3}, wantErr: "must not exceed PoolSize"},
+		{name: "negative timeout", conf: RedisConfig{Addr: "127.0.0.1:6379", ReadTimeout: -time.Second}, wantErr: "must not be negative"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.conf.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Validate() unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Validate() error = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRedisConfig_LoadWithDefaults(t *testing.T) {
+	var c RedisConfig
+	if err := conf.LoadFromYamlBytes([]byte("Addr: 127.0.0.1:6379\n"), &c); err != nil {
+		t.Fatalf("LoadFromYamlBytes failed: %v", err)
+	}
+	if c.PoolSize != 10 || c.MinIdleConns != 2 {
+		t.Errorf("unexpected pool defaults: PoolSize=%d MinIdleConns=%d", c.PoolSize, c.MinIdleConns)
+	}
+	if c.DialTimeout != 5*time.Second || c.ReadTimeout != 3*time.Second || c.WriteTimeout != 3*time.Second {
+		t.Errorf("unexpected timeout defaults: %v %v %v", c.DialTimeout, c.ReadTimeout, c.WriteTimeout)
+	}
+
+	var cluster RedisConfig
+	err := conf.LoadFromYamlBytes([]byte("ClusterAddrs:\n  - 127.0.0.1:7000\nDB: 2\n"), &cluster)
+	if err == nil || !strings.Contains(err.Error(), "DB must be 0 in cluster mode") {
+		t.Fatalf("expected cluster DB validation error, got %v", err)
+	}
+}
@@ -2,7 +2,9 @@ package gormc_test
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
 	"testing"
 	"time"
 
@@ -64,6 +66,42 @@ func setupTestEnv(t *testing.T) (*gorm.DB, *miniredis.Miniredis, gormc.CachedCon
 	return db, mr, cachedConn
 }
 
+// setupTestDB 创建内存 SQLite 数据库
+func setupTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	// 内存 SQLite 每个连接是独立的库，异步查询需要共享同一个连接
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("Failed to get sql.DB: %v", err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	if err := db.AutoMigrate(&TestUser{}); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+	return db
+}
+
+// setupTestCache 创建基于 miniredis 的 RedisCache
+func setupTestCache(t *testing.T, opts ...gormc.RedisCacheOption) (*miniredis.Miniredis, *gormc.RedisCache) {
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("Failed to start miniredis: %v", err)
+	}
+	cache, err := gormc.NewRedisCache(gormc.RedisConfig{Addr: mr.Addr()}, time.Minute, opts...)
+	if err != nil {
+		mr.Close()
+		t.Fatalf("Failed to create redis cache: %v", err)
+	}
+	t.Cleanup(func() {
+		cache.Close()
+		mr.Close()
+	})
+	return mr, cache
+}
+
 func TestRedisCache_BasicOperations(t *testing.T) {
 	db, mr, cachedConn := setupTestEnv(t)
 	defer mr.Close()
@@ -398,6 +436,33 @@ func TestRedisCache_Transaction(t *testing.T) {
 	}
 }
 
+func TestRedisCache_MaxValueSize(t *testing.T) {
+	ctx := context.Background()
+	big := strings.Repeat("x", 64)
+
+	mr, cache := setupTestCache(t, gormc.WithMaxValueSize(32, gormc.BigValueSkip))
+	mr.Set("big", `"old"`)
+	if err := cache.SetCtx(ctx, "big", big); err != nil {
+		t.Fatalf("skip policy should not return error, got %v", err)
+	}
+	if mr.Exists("big") {
+		t.Error("big value must not be cached and the old value must be dropped")
+	}
+	if err := cache.SetCtx(ctx, "small", "ok"); err != nil || !mr.Exists("small") {
+		t.Errorf("small value should be cached, err: %v", err)
+	}
+
+	_, cache = setupTestCache(t, gormc.WithMaxValueSize(32, gormc.BigValueReject))
+	err := cache.SetCtx(ctx, "big", big)
+	var tooLarge *gormc.ValueTooLargeError
+	if !errors.As(err, &tooLarge) || !errors.Is(err, gormc.ErrValueTooLarge) {
+		t.Fatalf("Expected ValueTooLargeError, got %v", err)
+	}
+	if tooLarge.Key != "big" || tooLarge.Size != len(big)+2 || tooLarge.Limit != 32 {
+		t.Errorf("unexpected error fields: %+v", tooLarge)
+	}
+}
+
 // 基准测试
 func BenchmarkRedisCache_Query(b *testing.B) {
 	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
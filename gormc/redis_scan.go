@@ -0,0 +1,194 @@
+package gormc
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// defaultScanBatchSize is the default SCAN COUNT hint and UNLINK batch size.
+const defaultScanBatchSize = 500
+
+// maxPurgeSamples is the number of matched keys kept in PurgeResult for inspection.
+const maxPurgeSamples = 10
+
+// ScanOptions controls how keys are iterated by ScanKeysCtx and PurgeKeysCtx.
+type ScanOptions struct {
+	Pattern   string // SCAN MATCH pattern, e.g. "cache:order:*"
+	BatchSize int64  // SCAN COUNT hint and UNLINK batch size, defaults to 500
+	RateLimit int    // maximum number of keys handled per second, 0 means unlimited
+	DryRun    bool   // only report the matched keys, don't delete them
+}
+
+// PurgeResult is the result of PurgeKeysCtx.
+type PurgeResult struct {
+	Matched int64    // number of keys returned by SCAN, may contain duplicates
+	Deleted int64    // number of keys removed by UNLINK, from either redis while migrating
+	Samples []string // the first matched keys, useful to verify the pattern in dry-run mode
+	DryRun  bool
+}
+
+// ScanKeysCtx iterates the keys matching opts.Pattern with SCAN and calls fn with each batch.
+// In cluster mode every master is scanned, fn is never called concurrently.
+func (c *RedisCache) ScanKeysCtx(ctx context.Context, opts ScanOptions, fn func(keys []string) error) error {
+	return scanKeys(ctx, c.primary(), opts, fn)
+}
+
+// scanKeys iterates the keys of client matching opts.Pattern, see ScanKeysCtx.
+func scanKeys(ctx context.Context, client redis.Cmdable, opts ScanOptions, fn func(keys []string) error) error {
+	if opts.Pattern == "" {
+		return errors.New("cache: scan pattern must not be empty")
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = defaultScanBatchSize
+	}
+
+	var mu sync.Mutex
+	limiter := newKeyRateLimiter(opts.RateLimit)
+	scan := func(ctx context.Context, client redis.Cmdable) error {
+		var cursor uint64
+		for {
+			keys, next, err := client.Scan(ctx, cursor, opts.Pattern, opts.BatchSize).Result()
+			if err != nil {
+				return err
+			}
+			if len(keys) > 0 {
+				if err := limiter.wait(ctx, len(keys)); err != nil {
+					return err
+				}
+				mu.Lock()
+				err = fn(keys)
+				mu.Unlock()
+				if err != nil {
+					return err
+				}
+			}
+			if next == 0 {
+				return nil
+			}
+			cursor = next
+		}
+	}
+
+	if cluster, ok := client.(*redis.ClusterClient); ok {
+		return cluster.ForEachMaster(ctx, func(ctx context.Context, client *redis.Client) error {
+			return scan(ctx, client)
+		})
+	}
+	return scan(ctx, client)
+}
+
+// PurgeKeysCtx deletes the keys matching opts.Pattern with UNLINK, which frees the memory asynchronously.
+// It is meant for deploy-time invalidation, e.g. after the JSON shape of a model changed.
+// With opts.DryRun the matched keys are only counted. While migrating, the keys are purged from both redis.
+func (c *RedisCache) PurgeKeysCtx(ctx context.Context, opts ScanOptions) (PurgeResult, error) {
+	result := PurgeResult{DryRun: opts.DryRun}
+
+	clients := []redis.Cmdable{c.primary()}
+	if secondary := c.secondary(); secondary != nil {
+		// while migrating the secondary is scanned too, the keys left there would be read back by the fallback
+		clients = append(clients, secondary)
+	}
+	for _, client := range clients {
+		if err := c.purgeKeys(ctx, client, opts, &result); err != nil {
+			return result, err
+		}
+	}
+	return result, nil
+}
+
+// purgeKeys deletes the keys of client matching opts.Pattern from the primary and the secondary.
+func (c *RedisCache) purgeKeys(ctx context.Context, client redis.Cmdable, opts ScanOptions, result *PurgeResult) error {
+	return scanKeys(ctx, client, opts, func(keys []string) error {
+		result.Matched += int64(len(keys))
+		for _, key := range keys {
+			if len(result.Samples) >= maxPurgeSamples {
+				break
+			}
+			result.Samples = append(result.Samples, key)
+		}
+		if opts.DryRun {
+			return nil
+		}
+
+		n, err := c.unlink(ctx, keys)
+		result.Deleted += n
+		return err
+	})
+}
+
+// unlink removes keys from the primary, and from the secondary while migrating.
+func (c *RedisCache) unlink(ctx context.Context, keys []string) (int64, error) {
+	n, err := unlinkKeys(ctx, c.primary(), keys)
+	if secondary := c.secondary(); secondary != nil {
+		sn, serr := unlinkKeys(ctx, secondary, keys)
+		if serr != nil {
+			metricMigration.Inc("secondary_error")
+		}
+		n += sn
+		err = errors.Join(err, serr)
+	}
+	return n, err
+}
+
+// unlinkKeys removes keys one command per key in a pipeline,
+// so that keys of different slots can be removed together in cluster mode.
+func unlinkKeys(ctx context.Context, client redis.Cmdable, keys []string) (int64, error) {
+	pipe := client.Pipeline()
+	cmds := make([]*redis.IntCmd, 0, len(keys))
+	for _, key := range keys {
+		cmds = append(cmds, pipe.Unlink(ctx, key))
+	}
+	_, err := pipe.Exec(ctx)
+
+	var n int64
+	for _, cmd := range cmds {
+		n += cmd.Val()
+	}
+	return n, err
+}
+
+// keyRateLimiter paces the number of keys handled per second.
+type keyRateLimiter struct {
+	mu   sync.Mutex
+	rate int
+	next time.Time
+}
+
+func newKeyRateLimiter(rate int) *keyRateLimiter {
+	if rate <= 0 {
+		return nil
+	}
+	return &keyRateLimiter{rate: rate}
+}
+
+// wait blocks until n more keys are allowed to be handled.
+func (l *keyRateLimiter) wait(ctx context.Context, n int) error {
+	if l == nil {
+		return nil
+	}
+
+	l.mu.Lock()
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	delay := l.next.Sub(now)
+	l.next = l.next.Add(time.Duration(n) * time.Second / time.Duration(l.rate))
+	l.mu.Unlock()
+
+	if delay <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,61 @@
+package gormc_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/huof6829/gorm-zero/gormc"
+)
+
+func TestRedisCache_PurgeKeys(t *testing.T) {
+	mr, cache := setupTestCache(t)
+	ctx := context.Background()
+
+	for i := 0; i < 25; i++ {
+		mr.Set(fmt.Sprintf("cache:order:%d", i), "{}")
+	}
+	mr.Set("cache:user:1", "{}")
+
+	// dry-run 只统计，不删除
+	result, err := cache.PurgeKeysCtx(ctx, gormc.ScanOptions{Pattern: "cache:order:*", BatchSize: 10, DryRun: true})
+	if err != nil {
+		t.Fatalf("PurgeKeysCtx dry-run failed: %v", err)
+	}
+	if result.Matched != 25 || result.Deleted != 0 || len(result.Samples) == 0 {
+		t.Errorf("unexpected dry-run result: %+v", result)
+	}
+	if !mr.Exists("cache:order:0") {
+		t.Error("dry-run must not delete keys")
+	}
+
+	// 分批遍历
+	var batches, scanned int
+	err = cache.ScanKeysCtx(ctx, gormc.ScanOptions{Pattern: "cache:order:*", BatchSize: 10}, func(keys []string) error {
+		batches++
+		scanned += len(keys)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ScanKeysCtx failed: %v", err)
+	}
+	if batches != 3 || scanned != 25 {
+		t.Errorf("Expected 25 keys in 3 batches, got %d keys in %d batches", scanned, batches)
+	}
+
+	// miniredis 的 SCAN 游标是偏移量，边扫边删会跳过 key（真实 Redis 不会），这里一次扫完
+	result, err = cache.PurgeKeysCtx(ctx, gormc.ScanOptions{Pattern: "cache:order:*", BatchSize: 100, RateLimit: 10000})
+	if err != nil {
+		t.Fatalf("PurgeKeysCtx failed: %v", err)
+	}
+	if result.Deleted != 25 {
+		t.Errorf("Expected 25 deleted keys, got %d", result.Deleted)
+	}
+	if keys := mr.Keys(); len(keys) != 1 || keys[0] != "cache:user:1" {
+		t.Errorf("Expected only cache:user:1 to remain, got %v", keys)
+	}
+
+	if _, err := cache.PurgeKeysCtx(ctx, gormc.ScanOptions{}); err == nil {
+		t.Error("Expected error for empty pattern")
+	}
+}
@@ -0,0 +1,232 @@
+package gormc
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/zeromicro/go-zero/core/logx"
+	"gorm.io/gorm"
+)
+
+// RegistryHealth is the result of the health check of a Registry, a nil error means the backend is healthy.
+type RegistryHealth struct {
+	DBs   map[string]error
+	Redis error
+}
+
+// Err returns the errors of the unhealthy backends, nil if all are healthy.
+func (h RegistryHealth) Err() error {
+	names := make([]string, 0, len(h.DBs))
+	for name := range h.DBs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var errs []error
+	for _, name := range names {
+		if err := h.DBs[name]; err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+		}
+	}
+	if h.Redis != nil {
+		errs = append(errs, fmt.Errorf("redis: %w", h.Redis))
+	}
+	return errors.Join(errs...)
+}
+
+// Registry holds named databases and their CachedConn sharing one RedisCache, and closes them together.
+// The cache keys and the table versions of each CachedConn are prefixed with the name of its database,
+// see WithNamespace, pass the same option to the models built on the databases.
+type Registry struct {
+	mu        sync.RWMutex
+	cache     *RedisCache
+	dbs       map[string]*gorm.DB
+	conns     map[string]CachedConn
+	exporters []*DBStatsExporter
+	closeOnce sync.Once
+	closeErr  error
+}
+
+// NewRegistry returns an empty Registry, cache may be nil if the databases are not cached.
+func NewRegistry(cache *RedisCache) *Registry {
+	return &Registry{
+		cache: cache,
+		dbs:   make(map[string]*gorm.DB),
+		conns: make(map[string]CachedConn),
+	}
+}
+
+// Add registers db as name, with a CachedConn built with WithNamespace(name) and opts if the registry has a cache.
+func (r *Registry) Add(name string, db *gorm.DB, opts ...ConnOption) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.dbs[name]; ok {
+		return fmt.Errorf("gormc: database %q is already registered", name)
+	}
+	r.dbs[name] = db
+	if r.cache != nil {
+		opts = append([]ConnOption{WithNamespace(name)}, opts...)
+		r.conns[name] = NewConnWithCache(db, r.cache, opts...)
+	}
+	return nil
+}
+
+// DB returns the database named name.
+func (r *Registry) DB(name string) (*gorm.DB, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	db, ok := r.dbs[name]
+	return db, ok
+}
+
+// MustDB returns the database named name, it panics if there is none.
+func (r *Registry) MustDB(name string) *gorm.DB {
+	db, ok := r.DB(name)
+	if !ok {
+		panic(fmt.Errorf("gormc: database %q is not registered", name))
+	}
+	return db
+}
+
+// Conn returns the CachedConn of the database named name.
+func (r *Registry) Conn(name string) (CachedConn, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	cc, ok := r.conns[name]
+	return cc, ok
+}
+
+// MustConn returns the CachedConn of the database named name, it panics if there is none.
+func (r *Registry) MustConn(name string) CachedConn {
+	cc, ok := r.Conn(name)
+	if !ok {
+		panic(fmt.Errorf("gormc: cached database %q is not registered", name))
+	}
+	return cc
+}
+
+// Cache returns the RedisCache shared by the databases, nil if none.
+func (r *Registry) Cache() *RedisCache {
+	return r.cache
+}
+
+// Names returns the names of the databases, sorted.
+func (r *Registry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := make([]string, 0, len(r.dbs))
+	for name := range r.dbs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Health pings all the databases and redis.
+func (r *Registry) Health(ctx context.Context) RegistryHealth {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	health := RegistryHealth{DBs: make(map[string]error, len(r.dbs))}
+	for name, db := range r.dbs {
+		sqlDB, err := db.DB()
+		if err == nil {
+			err = sqlDB.PingContext(ctx)
+		}
+		health.DBs[name] = err
+	}
+	if r.cache != nil {
+		health.Redis = r.cache.Ping(ctx)
+	}
+	return health
+}
+
+// Ping pings all the databases and redis, it returns the errors of the unhealthy ones.
+func (r *Registry) Ping(ctx context.Context) error {
+	return r.Health(ctx).Err()
+}
+
+// Stats returns the connection pool stats of all the databases.
+func (r *Registry) Stats() map[string]sql.DBStats {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	stats := make(map[string]sql.DBStats, len(r.dbs))
+	for name, db := range r.dbs {
+		if sqlDB, err := db.DB(); err == nil {
+			stats[name] = sqlDB.Stats()
+		}
+	}
+	return stats
+}
+
+// ExportStats publishes the pool stats of all the databases labeled by their names, until Close.
+func (r *Registry) ExportStats(interval time.Duration) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for name, db := range r.dbs {
+		exporter, err := ExportDBStats(name, db, interval)
+		if err != nil {
+			return err
+		}
+		r.exporters = append(r.exporters, exporter)
+	}
+	return nil
+}
+
+// Close closes all the databases, including the plugins holding connections such as replicas, then the cache.
+// It is safe to be called more than once.
+func (r *Registry) Close() error {
+	r.closeOnce.Do(func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+
+		for _, exporter := range r.exporters {
+			exporter.Stop()
+		}
+		var errs []error
+		for name, db := range r.dbs {
+			if err := closeDB(db); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", name, err))
+			}
+		}
+		if r.cache != nil {
+			errs = append(errs, r.cache.Close())
+		}
+		r.closeErr = errors.Join(errs...)
+	})
+	return r.closeErr
+}
+
+// Start does nothing, it makes Registry a go-zero service.Service.
+func (r *Registry) Start() {
+}
+
+// Stop closes r, it makes Registry a go-zero service.Service.
+func (r *Registry) Stop() {
+	if err := r.Close(); err != nil {
+		logx.Errorf("gormc: failed to close the databases: %v", err)
+	}
+}
+
+func closeDB(db *gorm.DB) error {
+	var errs []error
+	for _, plugin := range db.Config.Plugins {
+		if closer, ok := plugin.(io.Closer); ok {
+			errs = append(errs, closer.Close())
+		}
+	}
+	sqlDB, err := db.DB()
+	if err == nil {
+		err = sqlDB.Close()
+	}
+	return errors.Join(append(errs, err)...)
+}
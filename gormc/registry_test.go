@@ -0,0 +1,142 @@
+package gormc_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/huof6829/gorm-zero/gormc"
+	"gorm.io/gorm"
+)
+
+func TestRegistry(t *testing.T) {
+	_, cache := setupTestCache(t)
+	orders, analytics := setupTestDB(t), setupTestDB(t)
+
+	r := gormc.NewRegistry(cache)
+	if err := r.Add("orders", orders); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	if err := r.Add("analytics", analytics); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	// 名字重复
+	if err := r.Add("orders", analytics); err == nil {
+		t.Error("Expected an error for a duplicated name")
+	}
+
+	if names := r.Names(); len(names) != 2 || names[0] != "analytics" || names[1] != "orders" {
+		t.Errorf("unexpected names: %v", names)
+	}
+	if db, ok := r.DB("orders"); !ok || db != orders {
+		t.Error("Expected the orders database")
+	}
+	if _, ok := r.DB("missing"); ok {
+		t.Error("Expected no database named missing")
+	}
+	cc := r.MustConn("orders")
+	var user TestUser
+	err := cc.QueryCtx(context.Background(), &user, "cache:users:id:1", func(conn *gorm.DB) error {
+		return conn.Where("id = ?", 1).First(&user).Error
+	})
+	if !errors.Is(err, gormc.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+
+	if err := r.Ping(context.Background()); err != nil {
+		t.Errorf("Expected healthy, got %v", err)
+	}
+	if stats := r.Stats(); len(stats) != 2 || stats["orders"].MaxOpenConnections != 1 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+	if err := r.ExportStats(0); err != nil {
+		t.Fatalf("ExportStats failed: %v", err)
+	}
+
+	// 一起关闭，可以重复调用
+	if err := r.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if err := r.Close(); err != nil {
+		t.Fatalf("Close again failed: %v", err)
+	}
+	health := r.Health(context.Background())
+	if health.DBs["orders"] == nil || health.DBs["analytics"] == nil || health.Err() == nil {
+		t.Errorf("Expected the closed databases to be unhealthy, got %+v", health)
+	}
+}
+
+func TestRegistry_Namespace(t *testing.T) {
+	mr, cache := setupTestCache(t)
+	orders, analytics := setupTestDB(t), setupTestDB(t)
+	orders.Create(&TestUser{ID: 1, Name: "Alice"})
+	analytics.Create(&TestUser{ID: 1, Name: "Bob"})
+	ctx := context.Background()
+
+	r := gormc.NewRegistry(cache)
+	for name, db := range map[string]*gorm.DB{"orders": orders, "analytics": analytics} {
+		if err := r.Add(name, db, gormc.WithModelName("users"), gormc.WithTableVersions()); err != nil {
+			t.Fatalf("Add failed: %v", err)
+		}
+	}
+
+	// 同名的表在不同的库中不会共用缓存
+	for name, expected := range map[string]string{"orders": "Alice", "analytics": "Bob"} {
+		var user TestUser
+		err := r.MustConn(name).QueryCtx(ctx, &user, "cache:users:id:1", func(conn *gorm.DB) error {
+			return conn.Where("id = ?", 1).First(&user).Error
+		})
+		if err != nil || user.Name != expected {
+			t.Errorf("Expected %s from %s, got %q (%v)", expected, name, user.Name, err)
+		}
+		if !mr.Exists(name + ":cache:users:id:1") {
+			t.Errorf("Expected the cache key prefixed with %s", name)
+		}
+	}
+
+	// 唯一索引查到的主键 key 也带前缀
+	var user TestUser
+	err := r.MustConn("orders").QueryRowIndexCtx(ctx, &user, "cache:users:name:Alice", func(primary interface{}) string {
+		return fmt.Sprintf("cache:users:id:%v", primary)
+	}, func(conn *gorm.DB, v interface{}) (interface{}, error) {
+		if err := conn.Where("name = ?", "Alice").Take(v).Error; err != nil {
+			return nil, err
+		}
+		return v.(*TestUser).ID, nil
+	}, func(conn *gorm.DB, v, primary interface{}) error {
+		return conn.Where("id = ?", primary).Take(v).Error
+	})
+	if err != nil || user.Name != "Alice" {
+		t.Fatalf("QueryRowIndexCtx failed: %+v (%v)", user, err)
+	}
+	if !mr.Exists("orders:cache:users:name:Alice") || mr.Exists("cache:users:id:1") {
+		t.Error("Expected the index and primary keys prefixed with orders")
+	}
+
+	// CacheKey 返回实际的 redis key，供管理接口等直接操作 key 的代码使用
+	if key, err := r.MustConn("analytics").CacheKey(ctx, "cache:users:id:1"); err != nil || !mr.Exists(key) {
+		t.Errorf("Expected CacheKey to return the existing redis key, got %q (%v)", key, err)
+	}
+
+	// 表版本也按库区分
+	if err := r.MustConn("orders").ExecCtx(ctx, func(conn *gorm.DB) error {
+		return conn.Model(&TestUser{}).Where("id = ?", 1).Update("name", "Carol").Error
+	}, "cache:users:id:1"); err != nil {
+		t.Fatalf("ExecCtx failed: %v", err)
+	}
+	if mr.Exists("orders:cache:users:id:1") || !mr.Exists("analytics:cache:users:id:1") {
+		t.Error("Expected only the orders key to be deleted")
+	}
+	if !mr.Exists("gormc:table:version:orders:users") || mr.Exists("gormc:table:version:analytics:users") {
+		t.Error("Expected only the orders table version to be bumped")
+	}
+
+	// 未注册的名字直接 panic
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected MustDB to panic")
+		}
+	}()
+	r.MustDB("missing")
+}
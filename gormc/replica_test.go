@@ -0,0 +1,161 @@
+package gormc_test
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/huof6829/gorm-zero/gormc"
+	"github.com/huof6829/gorm-zero/gormc/plugins"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// openReplicaDB 打开一个文件 SQLite 库，并写入一行 name 用来区分主库和副本
+func openReplicaDB(t *testing.T, path, name string) *sql.DB {
+	db, err := gorm.Open(sqlite.Open(path), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	if err := db.AutoMigrate(&TestUser{}); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+	if err := db.Create(&TestUser{ID: 1, Name: name}).Error; err != nil {
+		t.Fatalf("Failed to insert: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("Failed to get sql.DB: %v", err)
+	}
+	t.Cleanup(func() {
+		sqlDB.Close()
+	})
+	return sqlDB
+}
+
+func setupReplicaEnv(t *testing.T, checkInterval time.Duration) (gormc.CachedConn, *sql.DB) {
+	dir := t.TempDir()
+	openReplicaDB(t, filepath.Join(dir, "primary.db"), "primary")
+	replica := openReplicaDB(t, filepath.Join(dir, "replica.db"), "replica")
+
+	db, err := gorm.Open(sqlite.Open(filepath.Join(dir, "primary.db")), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	plugin := plugins.NewReplicaPlugin([]gorm.Dialector{sqlite.Dialector{Conn: replica}},
+		plugins.ReplicaConf{CheckInterval: checkInterval})
+	if err := db.Use(plugin); err != nil {
+		t.Fatalf("Failed to use replicas: %v", err)
+	}
+	t.Cleanup(func() {
+		plugin.Close()
+	})
+
+	_, cache := setupTestCache(t)
+	return gormc.NewConnWithCache(db, cache), replica
+}
+
+func queryName(t *testing.T, ctx context.Context, cc gormc.CachedConn) string {
+	var user TestUser
+	if err := cc.QueryNoCacheCtx(ctx, func(conn *gorm.DB) error {
+		return conn.Where("id = ?", 1).First(&user).Error
+	}); err != nil {
+		t.Fatalf("QueryNoCacheCtx failed: %v", err)
+	}
+	return user.Name
+}
+
+func TestReplica_ReadWriteSplitting(t *testing.T) {
+	cc, _ := setupReplicaEnv(t, 0)
+	ctx := context.Background()
+
+	// 不走缓存的查询读副本
+	if name := queryName(t, ctx, cc); name != "replica" {
+		t.Errorf("Expected the replica, got %s", name)
+	}
+	// 强制读主库
+	if name := queryName(t, gormc.WithPrimary(ctx), cc); name != "primary" {
+		t.Errorf("Expected the primary, got %s", name)
+	}
+
+	// 写入主库
+	err := cc.ExecCtx(ctx, func(conn *gorm.DB) error {
+		return conn.Create(&TestUser{ID: 2, Name: "written"}).Error
+	})
+	if err != nil {
+		t.Fatalf("ExecCtx failed: %v", err)
+	}
+	var count int64
+	if err := cc.QueryNoCacheCtx(gormc.WithPrimary(ctx), func(conn *gorm.DB) error {
+		return conn.Model(&TestUser{}).Count(&count).Error
+	}); err != nil || count != 2 {
+		t.Errorf("Expected 2 rows in the primary, got %d (%v)", count, err)
+	}
+
+	// 事务内的查询走主库
+	var user TestUser
+	err = cc.Transact(func(tx *gorm.DB) error {
+		return tx.Where("id = ?", 1).First(&user).Error
+	})
+	if err != nil || user.Name != "primary" {
+		t.Errorf("Expected the primary in transactions, got %s (%v)", user.Name, err)
+	}
+
+	// 填充缓存的查询读主库，避免缓存副本延迟的旧数据
+	user = TestUser{}
+	err = cc.QueryCtx(ctx, &user, "cache:users:id:1", func(conn *gorm.DB) error {
+		return conn.Where("id = ?", 1).First(&user).Error
+	})
+	if err != nil || user.Name != "primary" {
+		t.Errorf("Expected the cached row from the primary, got %s (%v)", user.Name, err)
+	}
+
+	// 按表版本缓存的列表读副本
+	var users []TestUser
+	err = cc.QueryVersionedCtx(ctx, &users, "cache:users:list", []string{"users"}, func(conn *gorm.DB) error {
+		return conn.Find(&users).Error
+	})
+	if err != nil || len(users) != 1 || users[0].Name != "replica" {
+		t.Errorf("Expected the list from the replica, got %+v (%v)", users, err)
+	}
+}
+
+func TestReplica_Close(t *testing.T) {
+	cc, replica := setupReplicaEnv(t, 10*time.Millisecond)
+
+	// 关闭时副本连接池随插件一起关闭
+	if err := cc.CloseCtx(context.Background()); err != nil {
+		t.Fatalf("CloseCtx failed: %v", err)
+	}
+	if err := replica.Ping(); err == nil {
+		t.Error("Expected the replica pool to be closed")
+	}
+}
+
+func TestReplica_HealthCheck(t *testing.T) {
+	cc, replica := setupReplicaEnv(t, 10*time.Millisecond)
+	ctx := context.Background()
+
+	if name := queryName(t, ctx, cc); name != "replica" {
+		t.Fatalf("Expected the replica, got %s", name)
+	}
+
+	// 副本不可用后，读请求回落到主库
+	replica.Close()
+	deadline := time.Now().Add(time.Second)
+	for {
+		var user TestUser
+		err := cc.QueryNoCacheCtx(ctx, func(conn *gorm.DB) error {
+			return conn.Where("id = ?", 1).First(&user).Error
+		})
+		if err == nil && user.Name == "primary" {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected reads to fall back to the primary, got %s (%v)", user.Name, err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
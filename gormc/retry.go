@@ -0,0 +1,235 @@
+package gormc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/zeromicro/go-zero/core/logx"
+	"github.com/zeromicro/go-zero/core/mathx"
+)
+
+// RetryConf is the retry of establishing the connections to the database and redis on startup.
+// The zero value tries once, like without retry.
+type RetryConf struct {
+	MaxAttempts     int           `json:",optional"`      // 0 means no limit with Timeout, or a single attempt without
+	InitialInterval time.Duration `json:",default=500ms"` // 首次重试间隔
+	MaxInterval     time.Duration `json:",default=10s"`   // 最大重试间隔，每次翻倍直到该值
+	Jitter          float64       `json:",default=0.2"`   // 重试间隔的随机偏差比例，避免同时重连
+	Timeout         time.Duration `json:",optional"`      // 总超时时间，0 为不限制
+	// Degraded starts even if the backend is still unreachable after retrying, instead of failing:
+	// the database connects lazily on the first query, the cache is disabled until redis is reachable,
+	// then serves reads again once the invalidations skipped meanwhile are replayed.
+	Degraded bool `json:",optional"`
+}
+
+func (c RetryConf) withDefaults() RetryConf {
+	if c.InitialInterval <= 0 {
+		c.InitialInterval = 500 * time.Millisecond
+	}
+	if c.MaxInterval < c.InitialInterval {
+		c.MaxInterval = max(10*time.Second, c.InitialInterval)
+	}
+	if c.MaxAttempts <= 0 && c.Timeout <= 0 {
+		c.MaxAttempts = 1
+	}
+	return c
+}
+
+// RetryCtx calls fn until it succeeds, with exponential backoff and jitter between the attempts.
+// It gives up after conf.MaxAttempts attempts, when conf.Timeout elapses or ctx is done.
+// name describes what fn connects to in the logs.
+func RetryCtx(ctx context.Context, name string, conf RetryConf, fn func(ctx context.Context) error) error {
+	conf = conf.withDefaults()
+	if conf.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, conf.Timeout)
+		defer cancel()
+	}
+
+	jitter := mathx.NewUnstable(conf.Jitter)
+	interval := conf.InitialInterval
+	for attempt := 1; ; attempt++ {
+		err := fn(ctx)
+		if err == nil {
+			if attempt > 1 {
+				logx.Infof("gormc: connected to %s after %d attempts", name, attempt)
+			}
+			return nil
+		}
+		if conf.MaxAttempts > 0 && attempt >= conf.MaxAttempts {
+			if attempt == 1 {
+				return err
+			}
+			return fmt.Errorf("gormc: failed to connect to %s after %d attempts: %w", name, attempt, err)
+		}
+
+		wait := jitter.AroundDuration(interval)
+		logx.Errorf("gormc: attempt %d to connect to %s failed: %v, retrying in %s", attempt, name, err, wait)
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("gormc: failed to connect to %s after %d attempts: %w",
+				name, attempt, errors.Join(err, ctx.Err()))
+		}
+		interval = min(interval*2, conf.MaxInterval)
+	}
+}
+
+// maxSkippedKeys bounds the keys recorded while degraded, beyond it the cache can't be enabled
+// without purging it.
+const maxSkippedKeys = 100000
+
+// skippedInvalidations records the keys and tables whose invalidation was skipped while the cache was disabled
+// by a degraded start, so that they are invalidated before the cache serves reads again.
+type skippedInvalidations struct {
+	mu       sync.Mutex
+	keys     map[string]struct{}
+	tables   map[string]struct{}
+	overflow bool
+	taken    bool
+}
+
+func newSkippedInvalidations() *skippedInvalidations {
+	return &skippedInvalidations{
+		keys:   make(map[string]struct{}),
+		tables: make(map[string]struct{}),
+	}
+}
+
+// record records keys and tables, it returns false once they have been taken by the recovery,
+// the caller must invalidate them itself then. Without recording, e.g. disabled by SetMode, it returns true.
+func (s *skippedInvalidations) record(keys, tables []string) bool {
+	if s == nil {
+		return true
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.taken {
+		return false
+	}
+	for _, key := range keys {
+		if len(s.keys) >= maxSkippedKeys {
+			s.overflow = true
+			break
+		}
+		s.keys[key] = struct{}{}
+	}
+	for _, table := range tables {
+		s.tables[table] = struct{}{}
+	}
+	return true
+}
+
+// take returns the recorded keys and tables and stops recording.
+func (s *skippedInvalidations) take() (keys, tables []string, overflow bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.taken = true
+	for key := range s.keys {
+		keys = append(keys, key)
+	}
+	for table := range s.tables {
+		tables = append(tables, table)
+	}
+	s.keys, s.tables = nil, nil
+	return keys, tables, s.overflow
+}
+
+// withDegraded starts c with the cache disabled, and enables it once redis is reachable.
+// The invalidations skipped meanwhile are recorded and replayed before enabling it.
+func withDegraded(client redis.Cmdable, conf RedisConfig, err error) RedisCacheOption {
+	return func(c *RedisCache) {
+		logx.Errorf("gormc: starting with the cache disabled until redis is reachable: %v", err)
+		c.SetMode(CacheModeDisabled)
+		c.skipped = newSkippedInvalidations()
+		c.goAsync(func() {
+			c.waitRedis(client, conf)
+		})
+	}
+}
+
+// waitRedis pings redis every conf.Retry.MaxInterval until it is reachable, then recovers the cache,
+// unless the mode was changed meanwhile.
+func (c *RedisCache) waitRedis(client redis.Cmdable, conf RedisConfig) {
+	retry := conf.Retry.withDefaults()
+	ticker := time.NewTicker(retry.MaxInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-ticker.C:
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), conf.DialTimeout)
+		err := client.Ping(ctx).Err()
+		cancel()
+		if err != nil {
+			continue
+		}
+
+		// bypass-reads first, so that the writers invalidate again while the recorded keys are replayed
+		if !c.switchMode(CacheModeDisabled, CacheModeBypassReads) {
+			c.skipped.take()
+			return
+		}
+		c.replaySkipped(retry.MaxInterval)
+		return
+	}
+}
+
+// replaySkipped invalidates the recorded keys and tables, retrying every interval,
+// then switches the cache from bypass-reads to normal.
+func (c *RedisCache) replaySkipped(interval time.Duration) {
+	keys, tables, overflow := c.skipped.take()
+	if overflow {
+		logx.Errorf("gormc: more than %d invalidations were skipped while redis was unreachable, "+
+			"the cache stays in bypass-reads mode, purge it and switch it to normal", maxSkippedKeys)
+		return
+	}
+
+	for {
+		err := c.invalidate(keys, tables)
+		if err == nil {
+			break
+		}
+		logx.Errorf("gormc: failed to invalidate the keys skipped while redis was unreachable: %v", err)
+
+		timer := time.NewTimer(interval)
+		select {
+		case <-c.done:
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+
+	if c.switchMode(CacheModeBypassReads, CacheModeNormal) {
+		logx.Infof("gormc: redis is reachable, %d keys and %d tables invalidated, cache mode switched to normal",
+			len(keys), len(tables))
+	}
+}
+
+// invalidate deletes keys and bumps the versions of tables, in batches of defaultScanBatchSize keys.
+func (c *RedisCache) invalidate(keys, tables []string) error {
+	ctx := context.Background()
+	for start := 0; start < len(keys); start += defaultScanBatchSize {
+		batch := keys[start:min(start+defaultScanBatchSize, len(keys))]
+		c.evictLocal(batch...)
+		if err := c.del(ctx, batch...); err != nil {
+			return err
+		}
+	}
+	if len(tables) == 0 {
+		return nil
+	}
+	return c.incrTableVersions(ctx, tables)
+}
@@ -0,0 +1,133 @@
+package gormc_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/huof6829/gorm-zero/gormc"
+)
+
+func TestRetryCtx(t *testing.T) {
+	conf := gormc.RetryConf{
+		MaxAttempts:     3,
+		InitialInterval: time.Millisecond,
+		MaxInterval:     2 * time.Millisecond,
+		Jitter:          0.2,
+	}
+	errDown := errors.New("down")
+
+	// 第三次成功
+	attempts := 0
+	err := gormc.RetryCtx(context.Background(), "test", conf, func(ctx context.Context) error {
+		attempts++
+		if attempts < 3 {
+			return errDown
+		}
+		return nil
+	})
+	if err != nil || attempts != 3 {
+		t.Errorf("Expected success after 3 attempts, got %d attempts (%v)", attempts, err)
+	}
+
+	// 次数用完
+	attempts = 0
+	err = gormc.RetryCtx(context.Background(), "test", conf, func(ctx context.Context) error {
+		attempts++
+		return errDown
+	})
+	if !errors.Is(err, errDown) || attempts != 3 {
+		t.Errorf("Expected to give up after 3 attempts, got %d attempts (%v)", attempts, err)
+	}
+
+	// 零值只尝试一次
+	attempts = 0
+	err = gormc.RetryCtx(context.Background(), "test", gormc.RetryConf{}, func(ctx context.Context) error {
+		attempts++
+		return errDown
+	})
+	if !errors.Is(err, errDown) || attempts != 1 {
+		t.Errorf("Expected a single attempt, got %d attempts (%v)", attempts, err)
+	}
+
+	// 不限次数时按总超时放弃
+	conf = gormc.RetryConf{InitialInterval: time.Millisecond, MaxInterval: 5 * time.Millisecond, Timeout: 50 * time.Millisecond}
+	start := time.Now()
+	err = gormc.RetryCtx(context.Background(), "test", conf, func(ctx context.Context) error {
+		return errDown
+	})
+	if !errors.Is(err, context.DeadlineExceeded) || !errors.Is(err, errDown) {
+		t.Errorf("Expected the deadline to be exceeded, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected to give up around the timeout, took %s", elapsed)
+	}
+}
+
+func TestNewRedisCache_Degraded(t *testing.T) {
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("Failed to start miniredis: %v", err)
+	}
+	addr := mr.Addr()
+	// 上次运行留下的缓存
+	mr.Set("user:1", `{"ID":1,"Name":"Alice"}`)
+	mr.Set("gormc:table:version:users", "3")
+	mr.Close()
+
+	conf := gormc.RedisConfig{
+		Addr:        addr,
+		DialTimeout: 50 * time.Millisecond,
+		Retry: gormc.RetryConf{
+			MaxAttempts:     2,
+			InitialInterval: 10 * time.Millisecond,
+			MaxInterval:     20 * time.Millisecond,
+		},
+	}
+	if _, err := gormc.NewRedisCache(conf, time.Minute); err == nil {
+		t.Fatal("Expected an error without degraded mode")
+	}
+
+	// 降级启动：缓存关闭，redis 恢复后切回 normal
+	conf.Retry.Degraded = true
+	cache, err := gormc.NewRedisCache(conf, time.Minute)
+	if err != nil {
+		t.Fatalf("Expected to start degraded, got %v", err)
+	}
+	defer cache.Close()
+	if mode := cache.Mode(); mode != gormc.CacheModeDisabled {
+		t.Fatalf("Expected the cache disabled, got %s", mode)
+	}
+
+	// 关闭期间跳过的失效被记录下来
+	ctx := context.Background()
+	if err := cache.DelCtx(ctx, "user:1"); err != nil {
+		t.Fatalf("DelCtx failed: %v", err)
+	}
+	cc := gormc.NewConnWithCache(setupTestDB(t), cache)
+	if err := cc.BumpTableVersionsCtx(ctx, "users"); err != nil {
+		t.Fatalf("BumpTableVersionsCtx failed: %v", err)
+	}
+
+	if err := mr.Restart(); err != nil {
+		t.Fatalf("Failed to restart miniredis: %v", err)
+	}
+	defer mr.Close()
+	deadline := time.Now().Add(2 * time.Second)
+	for cache.Mode() != gormc.CacheModeNormal {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected the cache back to normal, got %s", cache.Mode())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// 切回 normal 之前重放了跳过的失效
+	if mr.Exists("user:1") {
+		t.Error("Expected the key deleted while disabled to be invalidated on recovery")
+	}
+	if version, _ := mr.Get("gormc:table:version:users"); version != "4" {
+		t.Errorf("Expected the table version bumped on recovery, got %q", version)
+	}
+}
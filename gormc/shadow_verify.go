@@ -0,0 +1,225 @@
+package gormc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"math/rand"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/zeromicro/go-zero/core/logx"
+	"gorm.io/gorm"
+)
+
+const (
+	shadowMatch    = "match"
+	shadowMismatch = "mismatch"
+	shadowError    = "error"
+	shadowDropped  = "dropped"
+	// shadowUnverified is a sampled cache hit of QueryCtx, whose query can't fill a fresh value.
+	shadowUnverified = "unverified"
+)
+
+type (
+	// ShadowVerifyConf is the configuration of shadow verification.
+	ShadowVerifyConf struct {
+		// SampleRate is the fraction of cache hits verified against the database, in (0, 1].
+		SampleRate float64
+		// DeleteMismatched deletes the cache entries that don't match the database.
+		DeleteMismatched bool
+		// Equal compares the cached and the fresh value, defaults to comparing the encoded bytes.
+		Equal func(cached, fresh interface{}) bool
+		// Timeout is the timeout of a verifying query, defaults to 3s.
+		Timeout time.Duration
+		// MaxConcurrency is the max number of verifying queries in flight, samples beyond are dropped, defaults to 4.
+		MaxConcurrency int
+		// MaxSampleKeys is the number of mismatched keys kept in ShadowVerifyStats, defaults to 20.
+		MaxSampleKeys int
+	}
+
+	// ShadowVerifyStats is the result of shadow verification.
+	ShadowVerifyStats struct {
+		Verified       int64    // number of verified cache hits
+		Mismatched     int64    // number of cache hits that don't match the database
+		Errors         int64    // number of verifying queries that failed
+		Dropped        int64    // number of samples dropped because of MaxConcurrency
+		Unverified     int64    // number of sampled cache hits of QueryCtx, which can't be verified
+		MismatchedKeys []string // the most recent mismatched keys
+	}
+
+	shadowVerifier struct {
+		conf       ShadowVerifyConf
+		sem        chan struct{}
+		verified   atomic.Int64
+		mismatched atomic.Int64
+		errors     atomic.Int64
+		dropped    atomic.Int64
+		unverified atomic.Int64
+		mu         sync.Mutex
+		keys       []string
+	}
+)
+
+// WithShadowVerify verifies a sample of the cache hits of QueryIntoCtx and QueryRowIndexCtx against the database,
+// by re-querying into a fresh value in the background.
+// The hits of QueryCtx are not verified, its query writes into variables captured by the caller,
+// its sampled hits are only counted as unverified, so that the models still using it can be found and regenerated.
+func WithShadowVerify(conf ShadowVerifyConf) ConnOption {
+	return func(cc *CachedConn) {
+		if conf.SampleRate <= 0 {
+			return
+		}
+		if conf.Timeout <= 0 {
+			conf.Timeout = 3 * time.Second
+		}
+		if conf.MaxConcurrency <= 0 {
+			conf.MaxConcurrency = 4
+		}
+		if conf.MaxSampleKeys <= 0 {
+			conf.MaxSampleKeys = 20
+		}
+		cc.verifier = &shadowVerifier{
+			conf: conf,
+			sem:  make(chan struct{}, conf.MaxConcurrency),
+		}
+	}
+}
+
+// ShadowVerifyStats returns the shadow verification stats, the zero value if it is not enabled.
+func (cc CachedConn) ShadowVerifyStats() ShadowVerifyStats {
+	if cc.verifier == nil {
+		return ShadowVerifyStats{}
+	}
+	return cc.verifier.stats()
+}
+
+func (sv *shadowVerifier) sample() bool {
+	return sv != nil && rand.Float64() < sv.conf.SampleRate
+}
+
+func (sv *shadowVerifier) stats() ShadowVerifyStats {
+	sv.mu.Lock()
+	keys := append([]string(nil), sv.keys...)
+	sv.mu.Unlock()
+
+	return ShadowVerifyStats{
+		Verified:       sv.verified.Load(),
+		Mismatched:     sv.mismatched.Load(),
+		Errors:         sv.errors.Load(),
+		Dropped:        sv.dropped.Load(),
+		Unverified:     sv.unverified.Load(),
+		MismatchedKeys: keys,
+	}
+}
+
+// compare compares the encoded cached and fresh values of type typ.
+func (sv *shadowVerifier) compare(typ reflect.Type, cached, fresh []byte) string {
+	if sv.conf.Equal == nil {
+		if bytes.Equal(cached, fresh) {
+			return shadowMatch
+		}
+		return shadowMismatch
+	}
+
+	cachedVal := reflect.New(typ).Interface()
+	freshVal := reflect.New(typ).Interface()
+	if json.Unmarshal(cached, cachedVal) != nil || json.Unmarshal(fresh, freshVal) != nil {
+		return shadowError
+	}
+	if sv.conf.Equal(cachedVal, freshVal) {
+		return shadowMatch
+	}
+	return shadowMismatch
+}
+
+func (sv *shadowVerifier) report(ctx context.Context, cache *RedisCache, result string, keys ...string) {
+	metricShadowVerify.Inc(result)
+
+	switch result {
+	case shadowError:
+		sv.errors.Add(1)
+		return
+	case shadowDropped:
+		sv.dropped.Add(1)
+		return
+	case shadowUnverified:
+		sv.unverified.Add(1)
+		return
+	}
+
+	sv.verified.Add(1)
+	if result == shadowMatch {
+		return
+	}
+
+	sv.mismatched.Add(1)
+	sv.mu.Lock()
+	sv.keys = append(sv.keys, keys...)
+	if n := len(sv.keys) - sv.conf.MaxSampleKeys; n > 0 {
+		sv.keys = sv.keys[n:]
+	}
+	sv.mu.Unlock()
+
+	logx.WithContext(ctx).Errorf("gormc: cached value of %v doesn't match the database", keys)
+	if sv.conf.DeleteMismatched {
+		if err := cache.DelCtx(ctx, keys...); err != nil {
+			logx.WithContext(ctx).Errorf("gormc: failed to delete mismatched keys %v: %v", keys, err)
+		}
+	}
+}
+
+// verifyPrimary re-queries a sampled cache hit of QueryRowIndexCtx with primaryQuery in the background.
+func (sv *shadowVerifier) verifyPrimary(ctx context.Context, cc CachedConn, v interface{}, primaryKey interface{},
+	primaryQuery PrimaryQueryCtxFn, keys ...string) {
+	sv.verify(ctx, cc, v, func(conn *gorm.DB, fresh interface{}) error {
+		return primaryQuery(conn, fresh, primaryKey)
+	}, keys...)
+}
+
+// verify re-queries a sampled cache hit into a fresh value in the background, v holds the cached value.
+// Samples beyond MaxConcurrency verifying queries in flight are dropped.
+func (sv *shadowVerifier) verify(ctx context.Context, cc CachedConn, v interface{}, query QueryIntoCtxFn,
+	keys ...string) {
+	cached, err := json.Marshal(v)
+	if err != nil {
+		return
+	}
+
+	select {
+	case sv.sem <- struct{}{}:
+	default:
+		sv.report(ctx, cc.cache, shadowDropped)
+		return
+	}
+
+	typ := reflect.TypeOf(v).Elem()
+	started := cc.cache.goAsync(func() {
+		defer func() {
+			<-sv.sem
+		}()
+
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), sv.conf.Timeout)
+		defer cancel()
+
+		result := shadowError
+		fresh := reflect.New(typ).Interface()
+		err := query(cc.db.WithContext(ctx), fresh)
+		switch {
+		case err == nil:
+			if freshBytes, err := json.Marshal(fresh); err == nil {
+				result = sv.compare(typ, cached, freshBytes)
+			}
+		case errors.Is(err, ErrNotFound):
+			// the row is gone, but it is still cached
+			result = shadowMismatch
+		}
+		sv.report(ctx, cc.cache, result, keys...)
+	})
+	if !started {
+		<-sv.sem
+	}
+}
@@ -0,0 +1,155 @@
+package gormc_test
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/huof6829/gorm-zero/gormc"
+	"gorm.io/gorm"
+)
+
+// waitFor 等待异步校验完成
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if cond() {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatal("condition not met in time")
+}
+
+func TestCachedConn_ShadowVerifyQuery(t *testing.T) {
+	db := setupTestDB(t)
+	mr, cache := setupTestCache(t)
+	cachedConn := gormc.NewConnWithCache(db, cache, gormc.WithShadowVerify(gormc.ShadowVerifyConf{
+		SampleRate:       1,
+		DeleteMismatched: true,
+	}))
+	ctx := context.Background()
+	db.Create(&TestUser{ID: 1, Name: "Alice"})
+
+	var user TestUser
+	query := func() error {
+		user = TestUser{}
+		return cachedConn.QueryIntoCtx(ctx, &user, "user:1", func(conn *gorm.DB, v interface{}) error {
+			return conn.Where("id = ?", 1).First(v).Error
+		})
+	}
+	if err := query(); err != nil {
+		t.Fatalf("QueryIntoCtx failed: %v", err)
+	}
+
+	// 命中且一致
+	if err := query(); err != nil {
+		t.Fatalf("QueryIntoCtx failed: %v", err)
+	}
+	waitFor(t, func() bool { return cachedConn.ShadowVerifyStats().Verified == 1 })
+
+	// QueryCtx 的查询写入调用方的变量，不做校验，只计入 Unverified
+	var other TestUser
+	if err := cachedConn.QueryCtx(ctx, &other, "user:1", func(conn *gorm.DB) error {
+		return conn.Where("id = ?", 1).First(&other).Error
+	}); err != nil {
+		t.Fatalf("QueryCtx failed: %v", err)
+	}
+	if stats := cachedConn.ShadowVerifyStats(); stats.Unverified != 1 || stats.Verified != 1 {
+		t.Fatalf("Expected the QueryCtx hit to be counted as unverified, got %+v", stats)
+	}
+
+	// 绕过缓存修改数据库，缓存变脏，命中仍返回缓存值，异步校验发现不一致
+	db.Model(&TestUser{}).Where("id = ?", 1).Update("name", "Bob")
+	if err := query(); err != nil || user.Name != "Alice" {
+		t.Fatalf("Expected the cached value Alice, got %q (%v)", user.Name, err)
+	}
+	waitFor(t, func() bool { return cachedConn.ShadowVerifyStats().Mismatched == 1 })
+	waitFor(t, func() bool { return !mr.Exists("user:1") })
+
+	stats := cachedConn.ShadowVerifyStats()
+	if stats.Verified != 2 || len(stats.MismatchedKeys) != 1 || stats.MismatchedKeys[0] != "user:1" {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+}
+
+func TestCachedConn_ShadowVerifyDropped(t *testing.T) {
+	db := setupTestDB(t)
+	_, cache := setupTestCache(t)
+	cachedConn := gormc.NewConnWithCache(db, cache, gormc.WithShadowVerify(gormc.ShadowVerifyConf{
+		SampleRate:     1,
+		MaxConcurrency: 1,
+	}))
+	ctx := context.Background()
+	db.Create(&TestUser{ID: 1, Name: "Alice"})
+
+	// 第一个校验查询阻塞，后续采样超过并发上限被丢弃，不阻塞调用方
+	release := make(chan struct{})
+	var calls atomic.Int32
+	query := func(conn *gorm.DB, v interface{}) error {
+		if calls.Add(1) == 2 {
+			<-release
+		}
+		return conn.Where("id = ?", 1).First(v).Error
+	}
+	var user TestUser
+	for i := 0; i < 3; i++ {
+		if err := cachedConn.QueryIntoCtx(ctx, &user, "user:1", query); err != nil {
+			t.Fatalf("QueryIntoCtx failed: %v", err)
+		}
+	}
+	waitFor(t, func() bool { return cachedConn.ShadowVerifyStats().Dropped == 1 })
+	close(release)
+	waitFor(t, func() bool { return cachedConn.ShadowVerifyStats().Verified == 1 })
+}
+
+func TestCachedConn_ShadowVerifyRowIndex(t *testing.T) {
+	db := setupTestDB(t)
+	mr, cache := setupTestCache(t)
+	cachedConn := gormc.NewConnWithCache(db, cache, gormc.WithShadowVerify(gormc.ShadowVerifyConf{
+		SampleRate:       1,
+		DeleteMismatched: true,
+		Equal: func(cached, fresh interface{}) bool {
+			return cached.(*TestUser).Name == fresh.(*TestUser).Name
+		},
+	}))
+	ctx := context.Background()
+	db.Create(&TestUser{ID: 1, Name: "Alice", Email: "alice@example.com"})
+
+	keyer := func(primary interface{}) string {
+		return fmt.Sprintf("user:id:%v", primary)
+	}
+	find := func() (TestUser, error) {
+		var user TestUser
+		err := cachedConn.QueryRowIndexCtx(ctx, &user, "user:email:alice@example.com", keyer,
+			func(conn *gorm.DB, v interface{}) (interface{}, error) {
+				if err := conn.Where("email = ?", "alice@example.com").Take(&user).Error; err != nil {
+					return nil, err
+				}
+				return user.ID, nil
+			}, func(conn *gorm.DB, v, primary interface{}) error {
+				return conn.Where("id = ?", primary).Take(v).Error
+			})
+		return user, err
+	}
+
+	if _, err := find(); err != nil {
+		t.Fatalf("QueryRowIndexCtx failed: %v", err)
+	}
+	db.Model(&TestUser{}).Where("id = ?", 1).Update("name", "Bob")
+
+	// 命中缓存返回旧值，异步校验发现不一致并删除
+	user, err := find()
+	if err != nil || user.Name != "Alice" {
+		t.Fatalf("Expected cached value Alice, got %q (%v)", user.Name, err)
+	}
+	waitFor(t, func() bool { return cachedConn.ShadowVerifyStats().Mismatched == 1 })
+	waitFor(t, func() bool { return !mr.Exists("user:id:1") && !mr.Exists("user:email:alice@example.com") })
+
+	if user, err = find(); err != nil || user.Name != "Bob" {
+		t.Fatalf("Expected database value Bob after invalidation, got %q (%v)", user.Name, err)
+	}
+}
@@ -0,0 +1,407 @@
+package gormc
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+const shardingCallbackName = "gormc:sharding"
+
+var (
+	// ErrMissingShardKey is returned for statements on a sharded table without the sharding key,
+	// unless the context is marked by WithCrossShard.
+	ErrMissingShardKey = errors.New("gormc: missing sharding key")
+	// ErrCrossShard is returned for statements writing rows of more than one shard.
+	ErrCrossShard = errors.New("gormc: statement across shards")
+)
+
+type (
+	shardKeyCtxKey   struct{}
+	shardCtxKey      struct{}
+	crossShardCtxKey struct{}
+
+	// ShardingConf configures the sharding of a table.
+	ShardingConf struct {
+		Table  string // logical table, the TableName of the model, e.g. orders
+		Key    string // sharding column, e.g. user_id
+		Shards int    // number of tables
+		// Format builds the name of a shard from the logical table and the shard number, defaults to %s_%02d,
+		// e.g. orders_00 to orders_63.
+		Format string
+		// Algorithm returns the shard of a sharding key value, defaults to the value modulo Shards
+		// for integers and the crc32 of strings.
+		Algorithm func(value interface{}) (int, error)
+		// Databases optionally spreads the shards over databases, shard i lives in Databases[i*len(Databases)/Shards].
+		// Statements inside transactions stay on the database the transaction was started on.
+		Databases []gorm.Dialector
+	}
+
+	// Sharding routes the statements on a table to its shards, register it with NewShardingPlugin.
+	Sharding struct {
+		conf  ShardingConf
+		pools []gorm.ConnPool
+	}
+
+	// ShardingPlugin routes the statements on sharded tables.
+	//
+	// The shard of a statement is taken from, in order, the context marked by WithShardKey or ForEachShard,
+	// the equality conditions on the sharding key, and the rows written by creates, updates and deletes.
+	// Statements without it fail with ErrMissingShardKey, unless the context is marked by WithCrossShard.
+	// Raw SQL is not routed.
+	ShardingPlugin struct {
+		shardings map[string]*Sharding
+		closeOnce sync.Once
+	}
+)
+
+// NewSharding returns the Sharding configured by conf.
+func NewSharding(conf ShardingConf) (*Sharding, error) {
+	if len(conf.Table) == 0 || len(conf.Key) == 0 || conf.Shards <= 0 {
+		return nil, errors.New("gormc: sharding needs a table, a key and the number of shards")
+	}
+	if len(conf.Databases) > conf.Shards {
+		return nil, errors.New("gormc: more sharding databases than shards")
+	}
+	if len(conf.Format) == 0 {
+		conf.Format = "%s_%02d"
+	}
+	return &Sharding{conf: conf}, nil
+}
+
+// WithShardKey marks ctx with the value of the sharding key, which routes the statements and the cache keys.
+func WithShardKey(ctx context.Context, value interface{}) context.Context {
+	return context.WithValue(ctx, shardKeyCtxKey{}, value)
+}
+
+// WithCrossShard allows the statements without the sharding key, they run against the logical table as is,
+// e.g. a view over the shards.
+func WithCrossShard(ctx context.Context) context.Context {
+	return context.WithValue(ctx, crossShardCtxKey{}, true)
+}
+
+// Table returns the logical table of s.
+func (s *Sharding) Table() string {
+	return s.conf.Table
+}
+
+// Shard returns the shard of the value of the sharding key.
+func (s *Sharding) Shard(value interface{}) (int, error) {
+	if s.conf.Algorithm != nil {
+		shard, err := s.conf.Algorithm(value)
+		if err != nil {
+			return 0, err
+		}
+		if shard < 0 || shard >= s.conf.Shards {
+			return 0, fmt.Errorf("gormc: shard %d of %s out of range", shard, s.conf.Table)
+		}
+		return shard, nil
+	}
+
+	rv := reflect.Indirect(reflect.ValueOf(value))
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n := rv.Int() % int64(s.conf.Shards)
+		if n < 0 {
+			n = -n
+		}
+		return int(n), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int(rv.Uint() % uint64(s.conf.Shards)), nil
+	case reflect.String:
+		return int(crc32.ChecksumIEEE([]byte(rv.String())) % uint32(s.conf.Shards)), nil
+	default:
+		return 0, fmt.Errorf("gormc: unsupported sharding key %v of %s", value, s.conf.Table)
+	}
+}
+
+// TableOf returns the table of shard.
+func (s *Sharding) TableOf(shard int) string {
+	return fmt.Sprintf(s.conf.Format, s.conf.Table, shard)
+}
+
+// Tables returns the tables of all the shards.
+func (s *Sharding) Tables() []string {
+	tables := make([]string, s.conf.Shards)
+	for i := range tables {
+		tables[i] = s.TableOf(i)
+	}
+	return tables
+}
+
+// ForEachShard calls fn with ctx routed to each shard in turn, it stops at the first error.
+func (s *Sharding) ForEachShard(ctx context.Context, fn func(ctx context.Context, shard int) error) error {
+	for i := 0; i < s.conf.Shards; i++ {
+		if err := fn(context.WithValue(ctx, shardCtxKey{}, i), i); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// shardFromContext returns the shard ctx is routed to.
+func (s *Sharding) shardFromContext(ctx context.Context) (int, bool, error) {
+	if ctx == nil {
+		return 0, false, nil
+	}
+	if shard, ok := ctx.Value(shardCtxKey{}).(int); ok {
+		return shard, true, nil
+	}
+	value := ctx.Value(shardKeyCtxKey{})
+	if value == nil {
+		return 0, false, nil
+	}
+	shard, err := s.Shard(value)
+	return shard, err == nil, err
+}
+
+// pool returns the connections of the database of shard, nil if the shards share the database.
+func (s *Sharding) pool(shard int) gorm.ConnPool {
+	if len(s.pools) == 0 {
+		return nil
+	}
+	return s.pools[shard*len(s.pools)/s.conf.Shards]
+}
+
+// NewShardingPlugin returns a ShardingPlugin routing the tables of shardings, register it with db.Use.
+func NewShardingPlugin(shardings ...*Sharding) *ShardingPlugin {
+	p := &ShardingPlugin{shardings: make(map[string]*Sharding, len(shardings))}
+	for _, s := range shardings {
+		p.shardings[s.conf.Table] = s
+	}
+	return p
+}
+
+func (p *ShardingPlugin) Name() string {
+	return "gormc-sharding-plugin"
+}
+
+func (p *ShardingPlugin) Initialize(db *gorm.DB) error {
+	for _, s := range p.shardings {
+		for _, dialector := range s.conf.Databases {
+			config := *db.Config
+			shardDB, err := gorm.Open(dialector, &config)
+			if err != nil {
+				return errors.Join(err, p.Close())
+			}
+			s.pools = append(s.pools, shardDB.ConnPool)
+		}
+	}
+
+	// before the transactions begin, so that they begin on the database of the shard
+	db.Callback().Create().Before("gorm:begin_transaction").Register(shardingCallbackName, p.routeWrite)
+	db.Callback().Update().Before("gorm:begin_transaction").Register(shardingCallbackName, p.routeWrite)
+	db.Callback().Delete().Before("gorm:begin_transaction").Register(shardingCallbackName, p.routeWrite)
+	db.Callback().Query().Before("gorm:query").Register(shardingCallbackName, p.routeRead)
+	db.Callback().Row().Before("gorm:row").Register(shardingCallbackName, p.routeRead)
+	return nil
+}
+
+// Close closes the databases of the shards.
+func (p *ShardingPlugin) Close() error {
+	var err error
+	p.closeOnce.Do(func() {
+		for _, s := range p.shardings {
+			for _, pool := range s.pools {
+				if sqlDB, ok := pool.(*sql.DB); ok {
+					err = errors.Join(err, sqlDB.Close())
+				}
+			}
+		}
+	})
+	return err
+}
+
+func (p *ShardingPlugin) routeRead(db *gorm.DB) {
+	p.route(db, false)
+}
+
+func (p *ShardingPlugin) routeWrite(db *gorm.DB) {
+	p.route(db, true)
+}
+
+func (p *ShardingPlugin) route(db *gorm.DB, write bool) {
+	stmt := db.Statement
+	s, ok := p.shardings[stmt.Table]
+	if !ok || db.Error != nil {
+		return
+	}
+
+	shard, found, err := s.shardOf(stmt, write)
+	if err != nil {
+		_ = db.AddError(err)
+		return
+	}
+	if !found {
+		if crossShard, _ := stmt.Context.Value(crossShardCtxKey{}).(bool); !crossShard {
+			_ = db.AddError(fmt.Errorf("%w of %s", ErrMissingShardKey, s.conf.Table))
+		}
+		return
+	}
+
+	stmt.Table = s.TableOf(shard)
+	if pool := s.pool(shard); pool != nil {
+		if _, inTx := stmt.ConnPool.(gorm.TxCommitter); !inTx {
+			stmt.ConnPool = pool
+		}
+	}
+}
+
+// shardOf returns the shard of stmt, the rows written are looked at for writes.
+func (s *Sharding) shardOf(stmt *gorm.Statement, write bool) (int, bool, error) {
+	if shard, found, err := s.shardFromContext(stmt.Context); found || err != nil {
+		return shard, found, err
+	}
+
+	if where, ok := stmt.Clauses["WHERE"].Expression.(clause.Where); ok {
+		if value, found := s.keyOf(where.Exprs); found {
+			shard, err := s.Shard(value)
+			return shard, err == nil, err
+		}
+	}
+
+	if !write || stmt.Schema == nil || !stmt.ReflectValue.IsValid() {
+		return 0, false, nil
+	}
+	field := stmt.Schema.LookUpField(s.conf.Key)
+	if field == nil {
+		return 0, false, nil
+	}
+	return s.shardOfRows(stmt, field)
+}
+
+func (s *Sharding) shardOfRows(stmt *gorm.Statement, field *schema.Field) (int, bool, error) {
+	rows := []reflect.Value{stmt.ReflectValue}
+	if kind := stmt.ReflectValue.Kind(); kind == reflect.Slice || kind == reflect.Array {
+		rows = rows[:0]
+		for i := 0; i < stmt.ReflectValue.Len(); i++ {
+			rows = append(rows, reflect.Indirect(stmt.ReflectValue.Index(i)))
+		}
+	}
+
+	shard, found := 0, false
+	for _, row := range rows {
+		if row.Kind() != reflect.Struct {
+			return 0, false, nil
+		}
+		value, zero := field.ValueOf(stmt.Context, row)
+		if zero {
+			return 0, false, nil
+		}
+		rowShard, err := s.Shard(value)
+		if err != nil {
+			return 0, false, err
+		}
+		if found && rowShard != shard {
+			return 0, false, fmt.Errorf("%w of %s", ErrCrossShard, s.conf.Table)
+		}
+		shard, found = rowShard, true
+	}
+	return shard, found, nil
+}
+
+// keyOf returns the value of the sharding key in the equality conditions of exprs.
+func (s *Sharding) keyOf(exprs []clause.Expression) (interface{}, bool) {
+	for _, expr := range exprs {
+		switch expr := expr.(type) {
+		case clause.Eq:
+			if s.isKey(expr.Column) && !isList(expr.Value) {
+				return expr.Value, true
+			}
+		case clause.Expr:
+			if value, found := s.keyOfSQL(expr.SQL, expr.Vars); found {
+				return value, true
+			}
+		case clause.AndConditions:
+			if value, found := s.keyOf(expr.Exprs); found {
+				return value, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// keyOfSQL returns the value of the sharding key in sql made of conditions joined by AND, e.g. user_id = ? AND id = ?.
+func (s *Sharding) keyOfSQL(sql string, vars []interface{}) (interface{}, bool) {
+	if strings.ContainsAny(sql, "()") || orRegexp.MatchString(sql) {
+		return nil, false
+	}
+	var index int
+	for _, cond := range andRegexp.Split(sql, -1) {
+		if s.isKeyCondition(cond) && index < len(vars) && !isList(vars[index]) {
+			return vars[index], true
+		}
+		index += strings.Count(cond, "?")
+	}
+	return nil, false
+}
+
+func (s *Sharding) isKey(column interface{}) bool {
+	switch column := column.(type) {
+	case clause.Column:
+		return column.Name == s.conf.Key
+	case string:
+		return s.isKeyCondition(column + " = ?")
+	default:
+		return false
+	}
+}
+
+var (
+	andRegexp = regexp.MustCompile(`(?i)\s+AND\s+`)
+	orRegexp  = regexp.MustCompile(`(?i)\s+OR\s+`)
+)
+
+var keyConditionRegexp = regexp.MustCompile("^\\s*(?:`?\"?\\w+`?\"?\\.)?`?\"?(\\w+)`?\"?\\s*=\\s*\\?\\s*$")
+
+// isKeyCondition reports whether sql is the equality condition on the sharding key, e.g. user_id = ?.
+func (s *Sharding) isKeyCondition(sql string) bool {
+	match := keyConditionRegexp.FindStringSubmatch(sql)
+	return len(match) == 2 && strings.EqualFold(match[1], s.conf.Key)
+}
+
+func isList(value interface{}) bool {
+	if _, ok := value.([]byte); ok {
+		return false
+	}
+	kind := reflect.ValueOf(value).Kind()
+	return kind == reflect.Slice || kind == reflect.Array
+}
+
+// WithSharding suffixes the cache keys with the shard of the context, marked by WithShardKey or ForEachShard,
+// so that the rows of different shards sharing a primary key don't collide.
+// The cache operations fail with ErrMissingShardKey without the shard in the context, even if the statements
+// could be routed by their conditions or rows, since the cache keys can't be matched to those.
+func WithSharding(s *Sharding) ConnOption {
+	return func(cc *CachedConn) {
+		cc.sharding = s
+	}
+}
+
+// shardSuffix returns the suffix of the cache keys for the shard of ctx, see WithSharding.
+func (cc CachedConn) shardSuffix(ctx context.Context) (string, error) {
+	if cc.sharding == nil {
+		return "", nil
+	}
+	shard, found, err := cc.sharding.shardFromContext(ctx)
+	if err != nil {
+		return "", err
+	}
+	if !found {
+		return "", fmt.Errorf("%w in the context of the cache keys of %s", ErrMissingShardKey, cc.sharding.conf.Table)
+	}
+	return ":shard:" + strconv.Itoa(shard), nil
+}
+
+var _ gorm.Plugin = &ShardingPlugin{}
@@ -0,0 +1,228 @@
+package gormc_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/huof6829/gorm-zero/gormc"
+	"gorm.io/gorm"
+)
+
+type TestOrder struct {
+	ID     int64  `gorm:"primaryKey"`
+	UserID int64  `gorm:"column:user_id"`
+	Item   string `gorm:"column:item"`
+}
+
+func (TestOrder) TableName() string {
+	return "orders"
+}
+
+// setupShardingDB 创建 orders_00 到 orders_03 四张分表并注册分表插件
+func setupShardingDB(t *testing.T) (*gorm.DB, *gormc.Sharding) {
+	db := setupTestDB(t)
+	sharding, err := gormc.NewSharding(gormc.ShardingConf{Table: "orders", Key: "user_id", Shards: 4})
+	if err != nil {
+		t.Fatalf("NewSharding failed: %v", err)
+	}
+	for _, table := range sharding.Tables() {
+		if err := db.Table(table).AutoMigrate(&TestOrder{}); err != nil {
+			t.Fatalf("Failed to migrate %s: %v", table, err)
+		}
+	}
+	if err := db.Use(gormc.NewShardingPlugin(sharding)); err != nil {
+		t.Fatalf("Failed to use sharding: %v", err)
+	}
+	return db, sharding
+}
+
+// countRows 统计分表中的行数，绕过分表插件直接查询物理表
+func countRows(t *testing.T, db *gorm.DB, table string) int64 {
+	var count int64
+	if err := db.Raw("SELECT COUNT(*) FROM " + table).Scan(&count).Error; err != nil {
+		t.Fatalf("Failed to count %s: %v", table, err)
+	}
+	return count
+}
+
+func TestSharding_Route(t *testing.T) {
+	db, _ := setupShardingDB(t)
+	ctx := context.Background()
+
+	// 写入时根据行中的 user_id 路由
+	if err := db.Create(&TestOrder{ID: 1, UserID: 5, Item: "book"}).Error; err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if n := countRows(t, db, "orders_01"); n != 1 {
+		t.Errorf("Expected 1 row in orders_01, got %d", n)
+	}
+
+	// 根据 WHERE 条件路由
+	var order TestOrder
+	if err := db.Where("user_id = ?", 5).First(&order).Error; err != nil {
+		t.Fatalf("Query by condition failed: %v", err)
+	}
+	if order.Item != "book" {
+		t.Errorf("Expected book, got %s", order.Item)
+	}
+
+	// 根据上下文中的分片键路由
+	order = TestOrder{}
+	if err := db.WithContext(gormc.WithShardKey(ctx, int64(5))).First(&order, 1).Error; err != nil {
+		t.Fatalf("Query by context failed: %v", err)
+	}
+	if order.Item != "book" {
+		t.Errorf("Expected book, got %s", order.Item)
+	}
+
+	if err := db.Model(&TestOrder{}).Where("user_id = ? AND id = ?", 5, 1).
+		Update("item", "pen").Error; err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if err := db.Where(&TestOrder{UserID: 5}).First(&order).Error; err != nil {
+		t.Fatalf("Query by struct condition failed: %v", err)
+	}
+	if order.Item != "pen" {
+		t.Errorf("Expected pen, got %s", order.Item)
+	}
+}
+
+func TestSharding_MissingKey(t *testing.T) {
+	db, sharding := setupShardingDB(t)
+	ctx := context.Background()
+
+	for _, order := range []TestOrder{{ID: 1, UserID: 1}, {ID: 2, UserID: 2}, {ID: 3, UserID: 6}} {
+		if err := db.Create(&order).Error; err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+	}
+
+	// 没有分片键的查询被拒绝
+	var orders []TestOrder
+	if err := db.Find(&orders).Error; !errors.Is(err, gormc.ErrMissingShardKey) {
+		t.Errorf("Expected ErrMissingShardKey, got %v", err)
+	}
+	if err := db.Where("user_id IN ?", []int64{1, 2}).Find(&orders).Error; !errors.Is(err, gormc.ErrMissingShardKey) {
+		t.Errorf("Expected ErrMissingShardKey for IN, got %v", err)
+	}
+
+	// 逐个分片查询
+	var total int
+	err := sharding.ForEachShard(ctx, func(ctx context.Context, shard int) error {
+		var orders []TestOrder
+		if err := db.WithContext(ctx).Find(&orders).Error; err != nil {
+			return err
+		}
+		total += len(orders)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ForEachShard failed: %v", err)
+	}
+	if total != 3 {
+		t.Errorf("Expected 3 orders, got %d", total)
+	}
+
+	// 允许跨分片时按逻辑表执行，这里没有 orders 表所以报表不存在
+	err = db.WithContext(gormc.WithCrossShard(ctx)).Find(&orders).Error
+	if err == nil || errors.Is(err, gormc.ErrMissingShardKey) {
+		t.Errorf("Expected the logical table to be queried, got %v", err)
+	}
+}
+
+func TestSharding_CrossShardWrite(t *testing.T) {
+	db, _ := setupShardingDB(t)
+
+	// 同一分片的批量写入
+	if err := db.Create([]TestOrder{{ID: 1, UserID: 2}, {ID: 2, UserID: 6}}).Error; err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if n := countRows(t, db, "orders_02"); n != 2 {
+		t.Errorf("Expected 2 rows in orders_02, got %d", n)
+	}
+
+	// 跨分片的批量写入被拒绝
+	err := db.Create([]TestOrder{{ID: 3, UserID: 1}, {ID: 4, UserID: 2}}).Error
+	if !errors.Is(err, gormc.ErrCrossShard) {
+		t.Errorf("Expected ErrCrossShard, got %v", err)
+	}
+}
+
+func TestSharding_CacheKey(t *testing.T) {
+	db, sharding := setupShardingDB(t)
+	mr, cache := setupTestCache(t)
+	cc := gormc.NewConnWithCache(db, cache, gormc.WithSharding(sharding))
+	ctx := gormc.WithShardKey(context.Background(), int64(5))
+
+	if err := db.Create(&TestOrder{ID: 1, UserID: 5, Item: "book"}).Error; err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	var order TestOrder
+	if err := cc.QueryCtx(ctx, &order, "cache:orders:id:1", func(conn *gorm.DB) error {
+		return conn.First(&order, 1).Error
+	}); err != nil {
+		t.Fatalf("QueryCtx failed: %v", err)
+	}
+	if order.Item != "book" {
+		t.Errorf("Expected book, got %s", order.Item)
+	}
+
+	// 缓存 key 带上分片后缀
+	if !mr.Exists("cache:orders:id:1:shard:1") {
+		t.Error("Expected the cache key suffixed with the shard")
+	}
+	if mr.Exists("cache:orders:id:1") {
+		t.Error("Expected no unsuffixed cache key")
+	}
+
+	if err := cc.DelCacheCtx(ctx, "cache:orders:id:1"); err != nil {
+		t.Fatalf("DelCacheCtx failed: %v", err)
+	}
+	if mr.Exists("cache:orders:id:1:shard:1") {
+		t.Error("Expected the sharded cache key deleted")
+	}
+}
+
+func TestSharding_CacheKeyMissingShard(t *testing.T) {
+	db, sharding := setupShardingDB(t)
+	mr, cache := setupTestCache(t)
+	cc := gormc.NewConnWithCache(db, cache, gormc.WithSharding(sharding))
+	ctx := context.Background()
+
+	order := TestOrder{ID: 1, UserID: 5, Item: "book"}
+	if err := db.Create(&order).Error; err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	mr.Set("cache:orders:id:1:shard:1", `{"ID":1,"UserID":5,"Item":"book"}`)
+
+	// 按行路由的写入没有上下文中的分片，不执行写入，避免分片缓存残留旧值
+	order.Item = "pen"
+	err := cc.ExecCtx(ctx, func(conn *gorm.DB) error {
+		return conn.Save(&order).Error
+	}, "cache:orders:id:1")
+	if !errors.Is(err, gormc.ErrMissingShardKey) {
+		t.Errorf("Expected ErrMissingShardKey, got %v", err)
+	}
+	var item string
+	db.Raw("SELECT item FROM orders_01 WHERE id = 1").Scan(&item)
+	if item != "book" {
+		t.Errorf("Expected the write to be skipped, got %s", item)
+	}
+
+	// 缓存读写同样需要分片
+	var got TestOrder
+	err = cc.QueryCtx(ctx, &got, "cache:orders:id:1", func(conn *gorm.DB) error {
+		return conn.Where("user_id = ? AND id = ?", 5, 1).First(&got).Error
+	})
+	if !errors.Is(err, gormc.ErrMissingShardKey) {
+		t.Errorf("Expected ErrMissingShardKey for QueryCtx, got %v", err)
+	}
+	if err := cc.DelCacheCtx(ctx, "cache:orders:id:1"); !errors.Is(err, gormc.ErrMissingShardKey) {
+		t.Errorf("Expected ErrMissingShardKey for DelCacheCtx, got %v", err)
+	}
+	if !mr.Exists("cache:orders:id:1:shard:1") {
+		t.Error("Expected the sharded cache key to be kept")
+	}
+}
@@ -0,0 +1,157 @@
+package gormc
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// tableVersionPrefix is the prefix of the redis keys holding the table versions.
+const tableVersionPrefix = "gormc:table:version:"
+
+// setVersionIfGreater keeps the copied versions monotonic when bumps race.
+// language=lua
+const setVersionIfGreater = `
+local current = tonumber(redis.call("GET", KEYS[1]) or "0")
+if current < tonumber(ARGV[1]) then
+	redis.call("SET", KEYS[1], ARGV[1])
+end
+return 1`
+
+// WithTableVersions makes ExecCtx bump the versions of tables after writing,
+// which invalidates the results cached by QueryVersionedCtx that depend on them.
+// tables defaults to the model name, which is the table name for generated models.
+func WithTableVersions(tables ...string) ConnOption {
+	return func(cc *CachedConn) {
+		cc.versioned = true
+		cc.tables = tables
+	}
+}
+
+// BumpTableVersionsCtx bumps the versions of tables, call it after writing tables without ExecCtx.
+func (cc CachedConn) BumpTableVersionsCtx(ctx context.Context, tables ...string) error {
+	if len(tables) == 0 {
+		return nil
+	}
+	tables = cc.versionedTables(tables)
+	if !cc.Mode().deletable() && cc.cache.skipped.record(nil, tables) {
+		return nil
+	}
+	return cc.cache.incrTableVersions(ctx, tables)
+}
+
+// versionedTables returns tables prefixed with the namespace of cc.
+func (cc CachedConn) versionedTables(tables []string) []string {
+	if len(cc.namespace) == 0 {
+		return tables
+	}
+	namespaced := make([]string, len(tables))
+	for i, table := range tables {
+		namespaced[i] = cc.namespace + table
+	}
+	return namespaced
+}
+
+// incrTableVersions increments the versions of tables in the primary and copies them into the secondary.
+func (c *RedisCache) incrTableVersions(ctx context.Context, tables []string) error {
+	cmds := make([]*redis.IntCmd, len(tables))
+	_, err := c.primary().Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, table := range tables {
+			cmds[i] = pipe.Incr(ctx, tableVersionPrefix+table)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// copy the versions rather than bumping them separately, so that they match after switching over
+	secondary := c.secondary()
+	if secondary == nil {
+		return nil
+	}
+	_, err = secondary.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, table := range tables {
+			pipe.Eval(ctx, setVersionIfGreater, []string{tableVersionPrefix + table}, cmds[i].Val())
+		}
+		return nil
+	})
+	return err
+}
+
+// TableVersionsCtx returns the versions of tables, 0 for tables never written.
+func (cc CachedConn) TableVersionsCtx(ctx context.Context, tables ...string) ([]string, error) {
+	tables = cc.versionedTables(tables)
+	// GET in a pipeline rather than MGET, the keys may live in different slots in cluster mode
+	cmds := make([]*redis.StringCmd, len(tables))
+	_, err := cc.cache.primary().Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, table := range tables {
+			cmds[i] = pipe.Get(ctx, tableVersionPrefix+table)
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return nil, err
+	}
+
+	versions := make([]string, len(tables))
+	for i, cmd := range cmds {
+		version, err := cmd.Result()
+		switch {
+		case err == nil:
+			versions[i] = version
+		case errors.Is(err, redis.Nil):
+			versions[i] = "0"
+		default:
+			return nil, err
+		}
+	}
+	return versions, nil
+}
+
+// QueryVersionedCtx unmarshals into v with given key and the versions of tables, or runs query and caches the result.
+// The result is cached under key suffixed with the table versions, so it is dropped implicitly when any of
+// tables is written through ExecCtx, use it for lists and counts whose cache keys can't be known on writes.
+// Without WithTableVersions, query always runs against the database.
+//
+// Unlike the row queries, query reads from the replicas unless ctx is marked by WithPrimary, since lists
+// and counts are the heavy reads replicas are for. A replica lagging behind a write may cache the old result
+// under the new versions until the next write or the expiry, mark ctx for lists that must be read after writes.
+func (cc CachedConn) QueryVersionedCtx(ctx context.Context, v interface{}, key string, tables []string,
+	query QueryCtxFn) (err error) {
+	ctx, span := cc.startSpan(ctx, "QueryVersioned")
+	defer func() {
+		endSpan(span, err)
+	}()
+
+	if !cc.versioned {
+		return query(cc.db.WithContext(ctx))
+	}
+
+	versions, err := cc.TableVersionsCtx(ctx, tables...)
+	if err != nil {
+		return err
+	}
+	if key, err = cc.CacheKey(ctx, key); err != nil {
+		return err
+	}
+	_, err = cc.take(ctx, v, key+":v"+strings.Join(versions, "."), func(v interface{}) error {
+		return query(cc.db.WithContext(ctx))
+	})
+	return err
+}
+
+// bumpTableVersions bumps the versions of the tables written by cc.
+func (cc CachedConn) bumpTableVersions(ctx context.Context) error {
+	if !cc.versioned {
+		return nil
+	}
+
+	tables := cc.tables
+	if len(tables) == 0 && len(cc.model) > 0 {
+		tables = []string{cc.model}
+	}
+	return cc.BumpTableVersionsCtx(ctx, tables...)
+}
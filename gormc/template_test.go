@@ -0,0 +1,98 @@
+package gormc_test
+
+import (
+	"bytes"
+	"go/ast"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"os"
+	"strings"
+	"testing"
+	"text/template"
+)
+
+// renderModelTemplates 渲染 model 模板并解析为 Go 语法树
+func renderModelTemplates(t *testing.T, data map[string]interface{}, names ...string) (*token.FileSet, *ast.File) {
+	var buf bytes.Buffer
+	for _, name := range names {
+		text, err := os.ReadFile("../template/v1/model/" + name)
+		if err != nil {
+			t.Fatalf("Failed to read %s: %v", name, err)
+		}
+		tpl, err := template.New(name).Parse(string(text))
+		if err != nil {
+			t.Fatalf("Failed to parse %s: %v", name, err)
+		}
+		if err := tpl.Execute(&buf, data); err != nil {
+			t.Fatalf("Failed to render %s: %v", name, err)
+		}
+	}
+
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "model.go", buf.Bytes(), 0)
+	if err != nil {
+		t.Fatalf("Failed to parse the rendered code: %v\n%s", err, buf.String())
+	}
+	return fset, file
+}
+
+func TestModelTemplate_ConnOptions(t *testing.T) {
+	fset, file := renderModelTemplates(t, map[string]interface{}{
+		"pkg":                   "model",
+		"withCache":             true,
+		"upperStartCamelObject": "User",
+		"lowerStartCamelObject": "user",
+		"table":                 "\"`users`\"",
+		"gormCreatedAt":         false,
+		"gormUpdatedAt":         false,
+	}, "model.tpl", "model-new.tpl")
+
+	funcs := make(map[string]*ast.FuncDecl)
+	for _, decl := range file.Decls {
+		if fn, ok := decl.(*ast.FuncDecl); ok && fn.Recv == nil {
+			funcs[fn.Name.Name] = fn
+		}
+	}
+
+	// 两个构造函数都接收 ConnOption，并透传给下一层
+	for name, callee := range map[string]string{
+		"NewUserModel": "newUserModel",
+		"newUserModel": "gormc.NewConnWithCache",
+	} {
+		fn, ok := funcs[name]
+		if !ok {
+			t.Fatalf("Expected %s to be generated", name)
+		}
+		params := fn.Type.Params.List
+		last := params[len(params)-1]
+		if _, ok := last.Type.(*ast.Ellipsis); !ok || len(last.Names) != 1 || last.Names[0].Name != "opts" {
+			t.Errorf("Expected %s to end with opts ...gormc.ConnOption", name)
+		}
+
+		forwarded := false
+		ast.Inspect(fn.Body, func(n ast.Node) bool {
+			call, ok := n.(*ast.CallExpr)
+			if !ok || !call.Ellipsis.IsValid() {
+				return true
+			}
+			var fun strings.Builder
+			printer.Fprint(&fun, fset, call.Fun)
+			if fun.String() == callee {
+				forwarded = true
+			}
+			return true
+		})
+		if !forwarded {
+			t.Errorf("Expected %s to forward opts... to %s", name, callee)
+		}
+	}
+
+	// 表版本需要通过 opts 开启，默认不增加写入的开销
+	ast.Inspect(funcs["newUserModel"].Body, func(n ast.Node) bool {
+		if sel, ok := n.(*ast.SelectorExpr); ok && sel.Sel.Name == "WithTableVersions" {
+			t.Error("Expected table versions to be opt-in")
+		}
+		return true
+	})
+}
@@ -0,0 +1,171 @@
+package gormc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/zeromicro/go-zero/core/logx"
+	"gorm.io/gorm"
+)
+
+// setIfAbsentScript sets KEYS[1] to ARGV[1] with the ttl ARGV[2] in milliseconds if it doesn't exist,
+// otherwise deletes it, and returns whether it was set.
+// language=lua
+var setIfAbsentScript = redis.NewScript(`
+if redis.call("EXISTS", KEYS[1]) == 1 then
+	redis.call("DEL", KEYS[1])
+	return 0
+end
+if tonumber(ARGV[2]) > 0 then
+	redis.call("SET", KEYS[1], ARGV[1], "PX", ARGV[2])
+else
+	redis.call("SET", KEYS[1], ARGV[1])
+end
+return 1
+`)
+
+// WithWriteThrough makes ExecWriteThroughCtx write the new row into the cache instead of deleting its keys,
+// so that a read after a write doesn't go to the database.
+//
+// A key is only written if it is absent, a cached key is deleted instead: two concurrent updates may write
+// the cache in the reverse order of their commits, overwriting would keep the older row until the expiry.
+// So it mostly saves the read after an insert, or after an update of a row that wasn't cached.
+// The cached value is v as given, not what the database stored: columns filled by the database
+// (defaults, triggers, auto update times, rounded precision) must be set in v by exec, or don't enable it.
+func WithWriteThrough() ConnOption {
+	return func(cc *CachedConn) {
+		cc.writeThrough = true
+	}
+}
+
+// ExecWriteThroughCtx runs given exec, then writes v, the row it wrote, into the cache.
+// keysFn is called after exec succeeds, it returns the cache key of the primary key first,
+// followed by the cache keys of the unique indexes, which are mapped to the primary key as QueryRowIndexCtx does.
+// staleKeys are deleted, e.g. the keys of the old unique index values and of cached lists.
+//
+// Without WithWriteThrough, all the keys are deleted like ExecCtx does.
+// Only call it when exec commits, values written inside an uncommitted transaction must be deleted instead.
+func (cc CachedConn) ExecWriteThroughCtx(ctx context.Context, execCtx ExecCtxFn, v interface{},
+	keysFn func() []string, staleKeys ...string) error {
+	if _, err := cc.shardSuffix(ctx); err != nil {
+		return err
+	}
+	if err := execCtx(cc.db.WithContext(ctx)); err != nil {
+		return err
+	}
+	if err := cc.bumpTableVersions(ctx); err != nil {
+		return err
+	}
+
+	keys := keysFn()
+	sharded, err := cc.cacheKeys(ctx, keys)
+	if err != nil {
+		return err
+	}
+	if staleKeys, err = cc.cacheKeys(ctx, staleKeys); err != nil {
+		return err
+	}
+	if !cc.writeThrough || len(keys) == 0 {
+		if err := cc.cache.DelCtx(ctx, append(sharded, staleKeys...)...); err != nil {
+			return err
+		}
+		cc.bloom.add(ctx, keys...)
+		return nil
+	}
+
+	if err := cc.writeThroughCtx(ctx, v, sharded, staleKeys); err != nil {
+		// never leave a partially written row behind
+		if delErr := cc.cache.DelCtx(ctx, append(sharded, staleKeys...)...); delErr != nil {
+			return errors.Join(err, delErr)
+		}
+		return err
+	}
+	cc.bloom.add(ctx, keys...)
+	return nil
+}
+
+func (cc CachedConn) writeThroughCtx(ctx context.Context, v interface{}, keys, staleKeys []string) error {
+	primary, err := cc.primaryValue(ctx, v)
+	if err != nil {
+		return err
+	}
+
+	written := make(map[string]struct{}, len(keys))
+	for _, key := range keys {
+		written[key] = struct{}{}
+	}
+	stale := make([]string, 0, len(staleKeys))
+	for _, key := range staleKeys {
+		if _, ok := written[key]; !ok {
+			stale = append(stale, key)
+		}
+	}
+	if err := cc.cache.DelCtx(ctx, stale...); err != nil {
+		return err
+	}
+
+	// same expiries as QueryRowIndexCtx, the row outlives the index keys pointing to it
+	primaryKey := keys[0]
+	policy := cc.policy(primaryKey)
+	expiry := cc.cache.expiryOf(policy)
+	if err := cc.cache.setIfAbsentCtx(ctx, primaryKey, v, expiry+cacheSafeGapBetweenIndexAndPrimary, policy); err != nil {
+		return err
+	}
+	for _, key := range keys[1:] {
+		policy := cc.policy(key)
+		if err := cc.cache.setIfAbsentCtx(ctx, key, primary, cc.cache.expiryOf(policy), policy); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setIfAbsentCtx sets key like setCtx if it doesn't exist, otherwise it deletes key.
+func (c *RedisCache) setIfAbsentCtx(ctx context.Context, key string, v interface{}, expire time.Duration,
+	policy CachePolicy) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to marshal value: %w", err)
+	}
+
+	return c.writeBytes(ctx, key, data, expire, policy, c.setIfAbsent)
+}
+
+// setIfAbsent writes the raw value of key into the primary if it doesn't exist there, otherwise deletes it.
+// While migrating, key is deleted from the secondary, which is only read when the primary misses.
+func (c *RedisCache) setIfAbsent(ctx context.Context, key string, data []byte, expire time.Duration) error {
+	if err := setIfAbsentScript.Run(ctx, c.primary(), []string{key}, data, expire.Milliseconds()).Err(); err != nil {
+		return err
+	}
+
+	if secondary := c.secondary(); secondary != nil {
+		if err := secondary.Del(ctx, key).Err(); err != nil {
+			metricMigration.Inc("secondary_error")
+			logx.WithContext(ctx).Errorf("gormc: failed to delete %q from the secondary redis: %v", key, err)
+		}
+	}
+	return nil
+}
+
+// primaryValue returns the value of the primary key of v, which is a pointer to a gorm model.
+func (cc CachedConn) primaryValue(ctx context.Context, v interface{}) (interface{}, error) {
+	stmt := &gorm.Statement{DB: cc.db}
+	if err := stmt.Parse(v); err != nil {
+		return nil, err
+	}
+	field := stmt.Schema.PrioritizedPrimaryField
+	if field == nil {
+		return nil, fmt.Errorf("gormc: %s has no primary key", stmt.Schema.Name)
+	}
+
+	value, zero := field.ValueOf(ctx, reflect.Indirect(reflect.ValueOf(v)))
+	if zero {
+		return nil, fmt.Errorf("gormc: primary key of %s is zero", stmt.Schema.Name)
+	}
+	return value, nil
+}
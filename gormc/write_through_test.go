@@ -0,0 +1,202 @@
+package gormc_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/huof6829/gorm-zero/gormc"
+	"gorm.io/gorm"
+)
+
+func userRowKeys(user *TestUser) []string {
+	return []string{fmt.Sprintf("user:id:%d", user.ID), "user:email:" + user.Email}
+}
+
+func TestCachedConn_ExecWriteThrough(t *testing.T) {
+	db := setupTestDB(t)
+	mr, cache := setupTestCache(t)
+	cachedConn := gormc.NewConnWithCache(db, cache, gormc.WithWriteThrough())
+	ctx := context.Background()
+
+	// 插入后直接写入缓存，自增 id 在 exec 之后才有
+	user := &TestUser{Name: "Alice", Email: "alice@example.com"}
+	err := cachedConn.ExecWriteThroughCtx(ctx, func(conn *gorm.DB) error {
+		return conn.Create(user).Error
+	}, user, func() []string {
+		return userRowKeys(user)
+	}, "user:list")
+	if err != nil {
+		t.Fatalf("ExecWriteThroughCtx failed: %v", err)
+	}
+	if got, _ := mr.Get("user:email:alice@example.com"); got != fmt.Sprint(user.ID) {
+		t.Errorf("Expected the index key to hold the primary key, got %q", got)
+	}
+
+	// 更新唯一索引，旧索引 key 被删除
+	mr.Set("user:list", "[]")
+	old := *user
+	user.Email = "alice@new.com"
+	err = cachedConn.ExecWriteThroughCtx(ctx, func(conn *gorm.DB) error {
+		return conn.Save(user).Error
+	}, user, func() []string {
+		return userRowKeys(user)
+	}, append(userRowKeys(&old), "user:list")...)
+	if err != nil {
+		t.Fatalf("ExecWriteThroughCtx failed: %v", err)
+	}
+	if mr.Exists("user:email:alice@example.com") || mr.Exists("user:list") {
+		t.Error("Expected the stale keys to be deleted")
+	}
+
+	// 已缓存的行不覆盖而是删除，新的索引 key 不存在则写入
+	if mr.Exists(fmt.Sprintf("user:id:%d", user.ID)) {
+		t.Error("Expected the cached row to be deleted instead of overwritten")
+	}
+	if got, _ := mr.Get("user:email:alice@new.com"); got != fmt.Sprint(user.ID) {
+		t.Errorf("Expected the new index key to hold the primary key, got %q", got)
+	}
+
+	// 读取命中索引，行只查询一次
+	queries := 0
+	var got TestUser
+	err = cachedConn.QueryRowIndexCtx(ctx, &got, "user:email:alice@new.com", func(primary interface{}) string {
+		return fmt.Sprintf("user:id:%v", primary)
+	}, func(conn *gorm.DB, v interface{}) (interface{}, error) {
+		queries++
+		return nil, conn.Where("email = ?", "alice@new.com").Take(v).Error
+	}, func(conn *gorm.DB, v, primary interface{}) error {
+		queries++
+		return conn.Where("id = ?", primary).Take(v).Error
+	})
+	if err != nil || got.Email != "alice@new.com" {
+		t.Fatalf("Expected the updated row, got %+v (%v)", got, err)
+	}
+	if queries != 1 {
+		t.Errorf("Expected only the row to be queried, got %d queries", queries)
+	}
+}
+
+func TestCachedConn_ExecWriteThroughOutOfOrder(t *testing.T) {
+	db := setupTestDB(t)
+	mr, cache := setupTestCache(t)
+	cachedConn := gormc.NewConnWithCache(db, cache, gormc.WithWriteThrough())
+	ctx := context.Background()
+
+	user := &TestUser{ID: 1, Name: "Alice", Email: "alice@example.com"}
+	db.Create(user)
+
+	// 更新 A 先提交，更新 B 后提交，但 B 先写入缓存
+	a := *user
+	a.Name = "A"
+	b := *user
+	b.Name = "B"
+	db.Save(&a)
+	err := cachedConn.ExecWriteThroughCtx(ctx, func(conn *gorm.DB) error {
+		return conn.Save(&b).Error
+	}, &b, func() []string {
+		return userRowKeys(&b)
+	})
+	if err != nil {
+		t.Fatalf("ExecWriteThroughCtx failed: %v", err)
+	}
+
+	// A 晚到的写入不能覆盖 B，缓存被删除，下次读取数据库
+	err = cachedConn.ExecWriteThroughCtx(ctx, func(conn *gorm.DB) error {
+		return nil
+	}, &a, func() []string {
+		return userRowKeys(&a)
+	})
+	if err != nil {
+		t.Fatalf("ExecWriteThroughCtx failed: %v", err)
+	}
+	if mr.Exists("user:id:1") {
+		t.Fatal("Expected the late write to delete the cached row")
+	}
+
+	var got TestUser
+	if err := cachedConn.QueryIntoCtx(ctx, &got, "user:id:1", func(conn *gorm.DB, v interface{}) error {
+		return conn.Where("id = ?", 1).Take(v).Error
+	}); err != nil || got.Name != "B" {
+		t.Fatalf("Expected the committed row B, got %+v (%v)", got, err)
+	}
+}
+
+func TestCachedConn_ExecWriteThroughDisabled(t *testing.T) {
+	db := setupTestDB(t)
+	mr, cache := setupTestCache(t)
+	cachedConn := gormc.NewConnWithCache(db, cache)
+	ctx := context.Background()
+
+	user := &TestUser{ID: 1, Name: "Alice", Email: "alice@example.com"}
+	db.Create(user)
+	mr.Set("user:id:1", "{}")
+
+	// 未开启时和 ExecCtx 一样删除
+	err := cachedConn.ExecWriteThroughCtx(ctx, func(conn *gorm.DB) error {
+		return conn.Save(user).Error
+	}, user, func() []string {
+		return userRowKeys(user)
+	})
+	if err != nil {
+		t.Fatalf("ExecWriteThroughCtx failed: %v", err)
+	}
+	if mr.Exists("user:id:1") || mr.Exists("user:email:alice@example.com") {
+		t.Error("Expected the keys to be deleted without write-through")
+	}
+}
+
+// writeThroughUserModel 模拟生成的 model，构造函数和 Insert 与模板一致
+type writeThroughUserModel struct {
+	gormc.CachedConn
+	table string
+}
+
+func newWriteThroughUserModel(db *gorm.DB, cache *gormc.RedisCache, opts ...gormc.ConnOption) *writeThroughUserModel {
+	opts = append([]gormc.ConnOption{gormc.WithModelName("users")}, opts...)
+	return &writeThroughUserModel{
+		CachedConn: gormc.NewConnWithCache(db, cache, opts...),
+		table:      "users",
+	}
+}
+
+func (m *writeThroughUserModel) Insert(ctx context.Context, tx *gorm.DB, data *TestUser) error {
+	if tx != nil {
+		return m.ExecWithKeysCtx(ctx, func(conn *gorm.DB) error {
+			return tx.Create(&data).Error
+		}, func() []string {
+			return userRowKeys(data)
+		})
+	}
+	return m.ExecWriteThroughCtx(ctx, func(conn *gorm.DB) error {
+		return conn.Create(&data).Error
+	}, data, func() []string {
+		return userRowKeys(data)
+	})
+}
+
+func TestGeneratedModel_WriteThrough(t *testing.T) {
+	db := setupTestDB(t)
+	mr, cache := setupTestCache(t)
+	ctx := context.Background()
+
+	// 通过构造函数的 opts 开启 write-through
+	m := newWriteThroughUserModel(db, cache, gormc.WithWriteThrough())
+	user := &TestUser{Name: "Alice", Email: "alice@example.com"}
+	if err := m.Insert(ctx, nil, user); err != nil {
+		t.Fatalf("Insert failed: %v", err)
+	}
+	if !mr.Exists(fmt.Sprintf("user:id:%d", user.ID)) {
+		t.Error("Expected the inserted row to be written into the cache")
+	}
+
+	// 不传 opts 时保持删除
+	m = newWriteThroughUserModel(db, cache)
+	user = &TestUser{Name: "Bob", Email: "bob@example.com"}
+	if err := m.Insert(ctx, nil, user); err != nil {
+		t.Fatalf("Insert failed: %v", err)
+	}
+	if mr.Exists(fmt.Sprintf("user:id:%d", user.ID)) {
+		t.Error("Expected no cache entry without write-through")
+	}
+}
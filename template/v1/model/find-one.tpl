@@ -2,8 +2,8 @@
 func (m *default{{.upperStartCamelObject}}Model) FindOne(ctx context.Context, {{.lowerStartCamelPrimaryKey}} {{.dataType}}) (*{{.upperStartCamelObject}}, error) {
 	{{if .withCache}}{{.cacheKey}}
 	var resp {{.upperStartCamelObject}}
-	err := m.QueryCtx(ctx, &resp, {{.cacheKeyVariable}}, func(conn *gorm.DB) error {
-    		return conn.Model(&{{.upperStartCamelObject}}{}).Where("{{.originalPrimaryKey}} = ?", {{.lowerStartCamelPrimaryKey}}).First(&resp).Error
+	err := m.QueryIntoCtx(ctx, &resp, {{.cacheKeyVariable}}, func(conn *gorm.DB, v interface{}) error {
+    		return conn.Model(&{{.upperStartCamelObject}}{}).Where("{{.originalPrimaryKey}} = ?", {{.lowerStartCamelPrimaryKey}}).First(v).Error
     	})
 	switch err {
 	case nil:
@@ -44,4 +44,19 @@ func (m *default{{.upperStartCamelObject}}Model) FindPageList(ctx context.Contex
 
                                       	res, total, err := pagex.FindPageListWithCountMultiOrderBy[{{.upperStartCamelObject}}](ctx, page, orderBys, orderKeys, formatDB)
                                       	return res, total, err{{end}}
+}
+
+// FindPageListCached is FindPageList with the list and the count cached until the table is written,
+// pass gormc.WithTableVersions() to New{{.upperStartCamelObject}}Model to enable the cache.
+func (m *default{{.upperStartCamelObject}}Model) FindPageListCached(ctx context.Context, page *pagex.ListReq, orderBys []pagex.OrderBy,
+	orderKeys map[string]string, whereClause func(db *gorm.DB) *gorm.DB) ([]{{.upperStartCamelObject}}, int64, error) {
+	{{if .withCache}}formatDB := func(conn *gorm.DB) (*gorm.DB, *gorm.DB) {
+    		db := conn.Model(&{{.upperStartCamelObject}}{})
+    		if whereClause != nil {
+    			db = whereClause(db)
+    		}
+    		return db, nil
+    	}
+    	res, total, err := pagex.FindPageListCached[{{.upperStartCamelObject}}](ctx, m, []string{m.table}, page, orderBys, orderKeys, formatDB)
+    	return res, total, err{{else}}return m.FindPageList(ctx, page, orderBys, orderKeys, whereClause){{end}}
 }
\ No newline at end of file
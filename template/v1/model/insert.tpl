@@ -3,24 +3,35 @@ func (m *default{{.upperStartCamelObject}}Model) GetCacheKeys(data *{{.upperStar
     if data == nil {
         return []string{}
     }
+    cacheKeys := m.getRowCacheKeys(data)
+    cacheKeys = append(cacheKeys, m.customCacheKeys(data)...)
+    return cacheKeys
+}
+
+// getRowCacheKeys returns the cache key of the primary key followed by the cache keys of the unique indexes.
+func (m *default{{.upperStartCamelObject}}Model) getRowCacheKeys(data *{{.upperStartCamelObject}}) []string {
     {{.keys}}
-    cacheKeys := []string{
+    return []string{
         {{.keyValues}},
     }
-    cacheKeys = append(cacheKeys, m.customCacheKeys(data)...)
-    return cacheKeys
 }
 {{end}}
 
 func (m *default{{.upperStartCamelObject}}Model) Insert(ctx context.Context, tx *gorm.DB, data *{{.upperStartCamelObject}}) error {
 	{{if .withCache}}
-    err := m.ExecCtx(ctx, func(conn *gorm.DB) error {
-		db := conn
-        if tx != nil {
-            db = tx
-        }
-        return db.Create(&data).Error
-	}, m.GetCacheKeys(data)...){{else}}db := m.conn
+    if tx != nil {
+        // not committed yet, only invalidate
+        return m.ExecWithKeysCtx(ctx, func(conn *gorm.DB) error {
+            return tx.Create(&data).Error
+        }, func() []string {
+            return m.GetCacheKeys(data)
+        })
+    }
+    err := m.ExecWriteThroughCtx(ctx, func(conn *gorm.DB) error {
+        return conn.Create(&data).Error
+    }, data, func() []string {
+        return m.getRowCacheKeys(data)
+    }, m.customCacheKeys(data)...){{else}}db := m.conn
         if tx != nil {
             db = tx
         }
@@ -29,15 +40,15 @@ func (m *default{{.upperStartCamelObject}}Model) Insert(ctx context.Context, tx
 }
 func (m *default{{.upperStartCamelObject}}Model) BatchInsert(ctx context.Context, tx *gorm.DB, news []{{.upperStartCamelObject}}) error {
 	{{if .withCache}}
-    err := batchx.BatchExecCtxV2(ctx, m, news, func(conn *gorm.DB) error {
-    db := conn
-    		for _, v := range news {
-    			if err := db.Create(&v).Error; err != nil {
+    // the keys are built after the insert, with the auto-increment ids set on news
+    err := batchx.BatchInsertCtx(ctx, m, news, func(db *gorm.DB) error {
+    		for i := range news {
+    			if err := db.Create(&news[i]).Error; err != nil {
     				return err
     			}
     		}
     		return nil
-    	},tx){{else}}db := m.conn
+    	}, tx){{else}}db := m.conn
         if tx != nil {
             db = tx
         }
@@ -2,8 +2,9 @@ func ({{.upperStartCamelObject}}) TableName() string {
     return strings.Trim({{.table}}, "`")
 }
 
-func new{{.upperStartCamelObject}}Model(db *gorm.DB{{if .withCache}}, cache *gormc.RedisCache{{end}}) *default{{.upperStartCamelObject}}Model {
-	{{if .withCache}}cachedConn := gormc.NewConnWithCache(db, cache)
+func new{{.upperStartCamelObject}}Model(db *gorm.DB{{if .withCache}}, cache *gormc.RedisCache, opts ...gormc.ConnOption{{end}}) *default{{.upperStartCamelObject}}Model {
+	{{if .withCache}}opts = append([]gormc.ConnOption{gormc.WithModelName(strings.Trim({{.table}}, "`"))}, opts...)
+	cachedConn := gormc.NewConnWithCache(db, cache, opts...)
 	return &default{{.upperStartCamelObject}}Model{
 		CachedConn: cachedConn,
 		table: strings.Trim({{.table}}, "`"),
@@ -44,8 +44,10 @@ func (s *{{.upperStartCamelObject}}) BeforeUpdate(tx *gorm.DB) error {
 	return nil
 }
 {{ end }}
-func New{{.upperStartCamelObject}}Model(conn *gorm.DB{{if .withCache}}, cache *gormc.RedisCache{{end}}) {{.upperStartCamelObject}}Model {
-	{{if .withCache}}defaultModel := new{{.upperStartCamelObject}}Model(conn, cache)
+// New{{.upperStartCamelObject}}Model returns a model for the database table.{{if .withCache}}
+// opts are applied after the defaults, e.g. gormc.WithWriteThrough().{{end}}
+func New{{.upperStartCamelObject}}Model(conn *gorm.DB{{if .withCache}}, cache *gormc.RedisCache, opts ...gormc.ConnOption{{end}}) {{.upperStartCamelObject}}Model {
+	{{if .withCache}}defaultModel := new{{.upperStartCamelObject}}Model(conn, cache, opts...)
 	return &custom{{.upperStartCamelObject}}Model{
 		default{{.upperStartCamelObject}}Model: defaultModel,
 	}{{else}}defaultModel := new{{.upperStartCamelObject}}Model(conn)
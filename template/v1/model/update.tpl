@@ -1,17 +1,22 @@
 
 func (m *default{{.upperStartCamelObject}}Model) Update(ctx context.Context, tx *gorm.DB, data *{{.upperStartCamelObject}}) error {
     {{if .withCache}}old, err := m.FindOne(ctx, data.{{.upperStartCamelPrimaryKey}})
-    if err != nil && errors.Is(err, ErrNotFound) {
+    if err != nil {
         return err
     }
-    clearKeys := append(m.GetCacheKeys(old), m.GetCacheKeys(data)...)
-    err = m.ExecCtx(ctx, func(conn *gorm.DB) error {
-        db := conn
-        if tx != nil {
-            db = tx
-        }
-        return db.Save(data).Error
-    }, clearKeys...){{else}}db := m.conn
+    if tx != nil {
+        // not committed yet, only invalidate
+        clearKeys := append(m.GetCacheKeys(old), m.GetCacheKeys(data)...)
+        return m.ExecCtx(ctx, func(conn *gorm.DB) error {
+            return tx.Save(data).Error
+        }, clearKeys...)
+    }
+    staleKeys := append(m.GetCacheKeys(old), m.customCacheKeys(data)...)
+    err = m.ExecWriteThroughCtx(ctx, func(conn *gorm.DB) error {
+        return conn.Save(data).Error
+    }, data, func() []string {
+        return m.getRowCacheKeys(data)
+    }, staleKeys...){{else}}db := m.conn
         if tx != nil {
             db = tx
         }